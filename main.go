@@ -13,6 +13,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
@@ -53,15 +54,18 @@ func runError(format string, args ...any) error {
 }
 
 type config struct {
-	Mount    string
-	Port     int
-	Bind     string
-	CORS     string
-	CertFile string
-	KeyFile  string
-	OpenAPI  string
-	Version  bool
-	Args     []string
+	Mount      string
+	Port       int
+	Bind       string
+	CORS       string
+	CertFile   string
+	KeyFile    string
+	OpenAPI    string
+	Version    bool
+	Precedence string
+	Seed       string
+	Env        string
+	Args       []string
 }
 
 func parseConfig(args []string) (config, error) {
@@ -79,6 +83,9 @@ func parseConfig(args []string) (config, error) {
 	flagSet.StringVar(&cfg.CertFile, "cert", "", "TLS certificate file (enables HTTPS)")
 	flagSet.StringVar(&cfg.KeyFile, "key", "", "TLS private key file")
 	flagSet.StringVar(&cfg.OpenAPI, "openapi", "", "OpenAPI 3 JSON/YAML file to seed stub routes")
+	flagSet.StringVar(&cfg.Env, "env", "", "environment from http-client.env.json for {{name}} references")
+	flagSet.StringVar(&cfg.Precedence, "precedence", string(mockhttp.PrecedenceSpecific), "duplicate route resolution: specific or rotate")
+	flagSet.StringVar(&cfg.Seed, "seed", "", "integer seed for reproducible generated values")
 	flagSet.BoolVar(&cfg.Version, "version", false, "print version and exit")
 	if err := flagSet.Parse(args); err != nil {
 		return config{}, usageError("failed to parse flags: %v", err)
@@ -113,13 +120,24 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer, logger *slog.
 	if cfg.CertFile != "" && cfg.KeyFile == "" || cfg.KeyFile != "" && cfg.CertFile == "" {
 		return usageError("both -cert and -key are required for TLS")
 	}
+	precedence, err := mockhttp.ParsePrecedence(cfg.Precedence)
+	if err != nil {
+		return usageError("invalid -precedence: %v", err)
+	}
+	var seed int64
+	if cfg.Seed != "" {
+		if seed, err = strconv.ParseInt(cfg.Seed, 10, 64); err != nil {
+			return usageError("invalid -seed %q: must be an integer", cfg.Seed)
+		}
+	}
 	if len(cfg.Args) == 0 && cfg.OpenAPI == "" {
 		if f, ok := stdin.(*os.File); ok && stdinIsTerminal(f) {
-			return usageError("missing request input\nusage: mock [-l mock] [-p 8080] [-b addr] [-cors *] [-cert c -key k] [-openapi spec.yaml] <file.http> [file.http...] | mock [-p 8080] <directory> | cat file.http | mock")
+			return usageError("missing request input\nusage: mock [-l mock] [-p 8080] [-b addr] [-cors *] [-cert c -key k] [-openapi spec.yaml] [-precedence specific|rotate] [-seed n] [-env dev] <file.http> [file.http...] | mock [-p 8080] <directory> | cat file.http | mock")
 		}
 	}
 
-	input, err := loadInput(cfg.Args, stdin, cfg.OpenAPI)
+	loader := restclient.Loader{Env: cfg.Env}
+	input, err := loadInput(loader, cfg.Args, stdin, cfg.OpenAPI)
 	if err != nil {
 		// Parse/load errors already include file:line; print them directly.
 		return runError("%v", err)
@@ -143,6 +161,10 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer, logger *slog.
 			return runError("failed to load static files: %v", err)
 		}
 		mockServer = mockhttp.New(input.Methods, logger)
+		mockServer.SetPrecedence(precedence)
+		if cfg.Seed != "" {
+			mockServer.SetSeed(seed)
+		}
 		handler = newHandler(mockServer, cfg.Mount, staticFS)
 		logger.Info("starting mock HTTP server",
 			"addr", listenAddress(cfg.Bind, cfg.Port),
@@ -152,9 +174,18 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer, logger *slog.
 
 		if files := input.WatchFiles; len(files) > 0 {
 			reload := func() {
-				reloadMockFiles(mockServer, files, input.OpenAPI, logger, stdout, stderr)
+				reloadMockFiles(mockServer, loader, files, input.OpenAPI, logger, stdout, stderr)
+			}
+			imports, err := loader.Imports(files)
+			if err != nil {
+				return runError("%v", err)
+			}
+			httpFiles := append(slices.Clone(files), imports...)
+			paths := resolveWatchPaths(httpFiles, restclient.FileDependencies(input.Methods))
+			if cfg.Env != "" {
+				// Env files may not exist yet; creating one also triggers a reload.
+				paths = resolveWatchPaths(append(paths, restclient.EnvFiles(httpFiles)...), nil)
 			}
-			paths := resolveWatchPaths(files, restclient.FileDependencies(input.Methods))
 			watchCloser, err = watchFiles(paths, reload, logger)
 			if err != nil {
 				return runError("failed to watch request files: %v", err)
@@ -248,7 +279,7 @@ type inputSource struct {
 	OpenAPI    string
 }
 
-func loadInput(args []string, stdin io.Reader, openAPI string) (inputSource, error) {
+func loadInput(loader restclient.Loader, args []string, stdin io.Reader, openAPI string) (inputSource, error) {
 	if openAPI != "" && len(args) == 0 {
 		methods, err := restclient.LoadOpenAPI(openAPI)
 		if err != nil {
@@ -284,7 +315,7 @@ func loadInput(args []string, stdin io.Reader, openAPI string) (inputSource, err
 		}
 		methods = append(methods, openAPIMethods...)
 	}
-	fileMethods, err := loadMethods(args, stdin)
+	fileMethods, err := loadMethods(loader, args, stdin)
 	if err != nil {
 		return inputSource{}, err
 	}
@@ -296,11 +327,11 @@ func loadInput(args []string, stdin io.Reader, openAPI string) (inputSource, err
 	return src, nil
 }
 
-func loadMethods(args []string, stdin io.Reader) ([]restclient.Method, error) {
+func loadMethods(loader restclient.Loader, args []string, stdin io.Reader) ([]restclient.Method, error) {
 	if len(args) > 0 {
-		return restclient.Load(args)
+		return loader.Load(args)
 	}
-	return restclient.Parse("<stdin>", stdin)
+	return loader.Parse("<stdin>", stdin)
 }
 
 func validateMethods(methods []restclient.Method, args []string) error {
@@ -334,6 +365,7 @@ func newHandler(mockServer *mockhttp.Server, mount string, staticFS fs.FS) http.
 	mux.HandleFunc(mountRoot+"events", mockServer.ServeEvents)
 	mux.HandleFunc(mountRoot+"clear", mockServer.ServeClear)
 	mux.HandleFunc(mountRoot+"routes", mockServer.ServeRoutes)
+	mux.HandleFunc(mountRoot+"scenarios", mockServer.ServeScenarios)
 	mux.Handle(mountRoot, http.StripPrefix(mountRoot, http.FileServer(http.FS(staticFS))))
 	mux.Handle("/", mockServer)
 	return mux
@@ -352,7 +384,7 @@ func withCORS(next http.Handler, origin string) http.Handler {
 	})
 }
 
-func reloadMockFiles(mockServer *mockhttp.Server, files []string, openAPI string, logger *slog.Logger, out, errOut io.Writer) {
+func reloadMockFiles(mockServer *mockhttp.Server, loader restclient.Loader, files []string, openAPI string, logger *slog.Logger, out, errOut io.Writer) {
 	load := func() ([]restclient.Method, error) {
 		var methods []restclient.Method
 		if openAPI != "" {
@@ -363,7 +395,7 @@ func reloadMockFiles(mockServer *mockhttp.Server, files []string, openAPI string
 			methods = append(methods, openAPIMethods...)
 		}
 		if len(files) > 0 {
-			fileMethods, err := restclient.Load(files)
+			fileMethods, err := loader.Load(files)
 			if err != nil {
 				return nil, err
 			}
@@ -403,7 +435,7 @@ func normalizeMountPath(mount string) string {
 func printMethods(w io.Writer, methods []restclient.Method) {
 	fmt.Fprintln(w, "Available mock methods:")
 	for _, method := range methods {
-		target := method.Path
+		target := method.Host + method.Path
 		if query := method.Query.Encode(); query != "" {
 			target += "?" + query
 		}
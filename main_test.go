@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"net"
@@ -11,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -30,7 +32,7 @@ ok
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	methods, err := loadMethods([]string{path}, strings.NewReader(""))
+	methods, err := loadMethods(restclient.Loader{}, []string{path}, strings.NewReader(""))
 	if err != nil {
 		t.Fatalf("loadMethods() error = %v", err)
 	}
@@ -46,7 +48,7 @@ ok
 }
 
 func TestLoadMethodsParsesStdinWhenNoFiles(t *testing.T) {
-	methods, err := loadMethods(nil, strings.NewReader(`### User
+	methods, err := loadMethods(restclient.Loader{}, nil, strings.NewReader(`### User
 POST /users
 
 created
@@ -67,7 +69,7 @@ created
 
 func TestLoadInputUsesSingleDirectoryAsStaticRoot(t *testing.T) {
 	dir := t.TempDir()
-	input, err := loadInput([]string{dir}, strings.NewReader(""), "")
+	input, err := loadInput(restclient.Loader{}, []string{dir}, strings.NewReader(""), "")
 	if err != nil {
 		t.Fatalf("loadInput() error = %v", err)
 	}
@@ -86,7 +88,7 @@ func TestLoadInputRejectsDirectoryMixedWithRequestFiles(t *testing.T) {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	_, err := loadInput([]string{dir, path}, strings.NewReader(""), "")
+	_, err := loadInput(restclient.Loader{}, []string{dir, path}, strings.NewReader(""), "")
 	if err == nil {
 		t.Fatal("loadInput() error = nil, want error")
 	}
@@ -180,6 +182,78 @@ func TestParseConfigAndVersion(t *testing.T) {
 	}
 }
 
+func TestLoadMethodsUsesSelectedEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, restclient.EnvFileName), []byte(`{"dev":{"prefix":"/api"}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	path := filepath.Join(dir, "api.http")
+	if err := os.WriteFile(path, []byte("### Users\nGET {{prefix}}/users\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg, err := parseConfig([]string{"-env", "dev", path})
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	methods, err := loadMethods(restclient.Loader{Env: cfg.Env}, cfg.Args, strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("loadMethods() error = %v", err)
+	}
+	if methods[0].Path != "/api/users" {
+		t.Fatalf("Path = %q, want /api/users", methods[0].Path)
+	}
+}
+
+func TestRunWatchesFilesWithSelectedEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, restclient.EnvFileName), []byte(`{"dev":{"host":"http://localhost"}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	path := filepath.Join(dir, "api.http")
+	if err := os.WriteFile(path, []byte("### Users\nGET {{host}}/users\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	// Hold the port so run() gets past watch setup and then fails to listen
+	// instead of serving until a signal arrives.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	var stdout bytes.Buffer
+	err = run([]string{"-env", "dev", "-b", "127.0.0.1", "-p", port, path}, strings.NewReader(""), &stdout, io.Discard, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err == nil || !strings.Contains(err.Error(), "server failed") {
+		t.Fatalf("run() error = %v, want only the listen failure", err)
+	}
+	if !strings.Contains(stdout.String(), "/users") {
+		t.Fatalf("stdout = %q, want the /users route", stdout.String())
+	}
+}
+
+func TestRunRejectsInvalidSeed(t *testing.T) {
+	err := run([]string{"-seed", "abc", "examples/user.http"}, strings.NewReader(""), io.Discard, io.Discard, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) || exitErr.code != 2 {
+		t.Fatalf("run() error = %v, want usage error", err)
+	}
+	if !strings.Contains(err.Error(), "invalid -seed") {
+		t.Fatalf("error = %q, want invalid -seed", err)
+	}
+}
+
+func TestRunRejectsUnknownPrecedence(t *testing.T) {
+	err := run([]string{"-precedence", "random", "examples/user.http"}, strings.NewReader(""), io.Discard, io.Discard, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var exitErr *exitError
+	if !errors.As(err, &exitErr) || exitErr.code != 2 {
+		t.Fatalf("run() error = %v, want usage error", err)
+	}
+	if !strings.Contains(err.Error(), "invalid -precedence") {
+		t.Fatalf("error = %q, want invalid -precedence", err)
+	}
+}
+
 func TestParseConfigDefaultPortWithoutEnv(t *testing.T) {
 	t.Setenv("MOCK_PORT", "")
 	cfg, err := parseConfig([]string{"api.http"})
@@ -290,6 +364,12 @@ func TestPrintMethods(t *testing.T) {
 			Path:   "/names",
 			Query:  url.Values{"type": []string{"cat"}},
 		},
+		{
+			Name:   "Get Asset",
+			Method: http.MethodGet,
+			Host:   "*.cdn.example.test",
+			Path:   "/assets/**",
+		},
 	}
 
 	var output bytes.Buffer
@@ -302,6 +382,7 @@ func TestPrintMethods(t *testing.T) {
 		"Create User",
 		"GET     /names?type=cat",
 		"Get Cats",
+		"GET     *.cdn.example.test/assets/**",
 	} {
 		if !strings.Contains(got, want) {
 			t.Fatalf("printMethods() output = %q, want to contain %q", got, want)
@@ -478,7 +559,7 @@ ok
 	server := mockhttp.New(nil, logger)
 
 	var output bytes.Buffer
-	reloadMockFiles(server, []string{path}, "", logger, &output, io.Discard)
+	reloadMockFiles(server, restclient.Loader{}, []string{path}, "", logger, &output, io.Discard)
 
 	response := httptest.NewRecorder()
 	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users", nil))
@@ -517,7 +598,7 @@ ok
 
 	var output bytes.Buffer
 	var errOut bytes.Buffer
-	reloadMockFiles(server, []string{path}, "", logger, &output, &errOut)
+	reloadMockFiles(server, restclient.Loader{}, []string{path}, "", logger, &output, &errOut)
 	if output.Len() != 0 {
 		t.Fatalf("output = %q, want empty on failed reload", output.String())
 	}
@@ -608,6 +689,11 @@ ok
 	if routes.Code != http.StatusOK || !strings.Contains(routes.Body.String(), "/users") {
 		t.Fatalf("routes status = %d body = %q", routes.Code, routes.Body.String())
 	}
+	scenarios := httptest.NewRecorder()
+	handler.ServeHTTP(scenarios, httptest.NewRequest(http.MethodGet, "/admin/scenarios", nil))
+	if scenarios.Code != http.StatusOK || strings.TrimSpace(scenarios.Body.String()) != "[]" {
+		t.Fatalf("scenarios status = %d body = %q", scenarios.Code, scenarios.Body.String())
+	}
 }
 
 func TestWithCORS(t *testing.T) {
@@ -633,6 +719,26 @@ func TestResolveWatchPathsIncludesDependencies(t *testing.T) {
 	}
 }
 
+func TestResolveWatchPathsIncludesImports(t *testing.T) {
+	dir := t.TempDir()
+	common := filepath.Join(dir, "common.http")
+	api := filepath.Join(dir, "api.http")
+	if err := os.WriteFile(common, []byte("### Health\nGET /health\n\nok\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(api, []byte("# @import common.http\n### A\nGET /a\n\na\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	imports, err := restclient.Imports([]string{api})
+	if err != nil {
+		t.Fatalf("Imports() error = %v", err)
+	}
+	paths := resolveWatchPaths(append([]string{api}, imports...), nil)
+	if len(paths) != 2 || paths[1] != common {
+		t.Fatalf("paths = %#v, want api.http and imported common.http", paths)
+	}
+}
+
 func TestEndToEndReloadServesNewRoutes(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "api.http")
@@ -648,7 +754,7 @@ func TestEndToEndReloadServesNewRoutes(t *testing.T) {
 
 	changed := make(chan struct{}, 1)
 	closer, err := watchFiles([]string{path}, func() {
-		reloadMockFiles(server, []string{path}, "", logger, io.Discard, io.Discard)
+		reloadMockFiles(server, restclient.Loader{}, []string{path}, "", logger, io.Discard, io.Discard)
 		select {
 		case changed <- struct{}{}:
 		default:
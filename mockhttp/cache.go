@@ -0,0 +1,31 @@
+package mockhttp
+
+import "sync"
+
+// maxCachedTexts bounds a textCache. Entries are keyed by full body text,
+// including $file contents, so without a bound every edit to a served file
+// would leave another entry behind for the life of the process.
+const maxCachedTexts = 256
+
+// textCache memoizes values parsed from response text. When it is full it
+// starts over rather than tracking recency; reparsing a body is cheap.
+type textCache[V any] struct {
+	mu      sync.Mutex
+	entries map[string]V
+}
+
+func (c *textCache[V]) load(text string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.entries[text]
+	return value, ok
+}
+
+func (c *textCache[V]) store(text string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxCachedTexts {
+		c.entries = make(map[string]V)
+	}
+	c.entries[text] = value
+}
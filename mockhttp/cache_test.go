@@ -0,0 +1,20 @@
+package mockhttp
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestTextCacheIsBounded(t *testing.T) {
+	var cache textCache[int]
+	for i := range maxCachedTexts * 3 {
+		cache.store(strconv.Itoa(i), i)
+		if len(cache.entries) > maxCachedTexts {
+			t.Fatalf("after %d stores cache holds %d entries, want at most %d", i+1, len(cache.entries), maxCachedTexts)
+		}
+	}
+	last := strconv.Itoa(maxCachedTexts*3 - 1)
+	if value, ok := cache.load(last); !ok || value != maxCachedTexts*3-1 {
+		t.Fatalf("load(%q) = %d, %v, want latest entry", last, value, ok)
+	}
+}
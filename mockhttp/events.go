@@ -1,10 +1,12 @@
 package mockhttp
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
@@ -34,11 +36,35 @@ type EventResponse struct {
 }
 
 // RouteInfo is a JSON-friendly description of a configured mock route.
+// Precedence is the route's rank when matches are resolved by specificity:
+// 1 is the most specific, and routes sharing a rank rotate with each other.
+// Variables and Headers are the effective configuration, including values
+// inherited from the file preamble and named in the Inherited fields.
 type RouteInfo struct {
-	Name   string `json:"name"`
-	Method string `json:"method"`
-	Path   string `json:"path"`
-	Query  string `json:"query,omitzero"`
+	Name               string            `json:"name"`
+	Method             string            `json:"method"`
+	Host               string            `json:"host,omitzero"`
+	Path               string            `json:"path"`
+	Query              string            `json:"query,omitzero"`
+	Precedence         int               `json:"precedence,omitzero"`
+	Variables          map[string]string `json:"variables,omitempty"`
+	Headers            http.Header       `json:"headers,omitempty"`
+	InheritedVariables []string          `json:"inheritedVariables,omitempty"`
+	InheritedHeaders   []string          `json:"inheritedHeaders,omitempty"`
+	Rotations          []RotationInfo    `json:"rotations,omitempty"`
+}
+
+// RotationInfo is the state of one rotation counter a route takes part in.
+// Scope is the $rotateBy value the counter belongs to, such as "path /users/1",
+// and is empty for the group-wide counter. Position is this route's 1-based
+// place among the Size routes of its group, Next is the place that serves the
+// next request, and Count is how many requests the counter has served.
+type RotationInfo struct {
+	Scope    string `json:"scope,omitzero"`
+	Position int    `json:"position"`
+	Next     int    `json:"next"`
+	Size     int    `json:"size"`
+	Count    int    `json:"count"`
 }
 
 func (s *Server) ServeEvents(w http.ResponseWriter, r *http.Request) {
@@ -87,7 +113,8 @@ func (s *Server) ServeEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ServeClear handles POST to clear the in-memory request log and rotation counters.
+// ServeClear handles POST to clear the in-memory request log, rotation
+// counters, scenario states and $resource collections.
 func (s *Server) ServeClear(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.Header().Set("Allow", http.MethodPost)
@@ -96,6 +123,8 @@ func (s *Server) ServeClear(w http.ResponseWriter, r *http.Request) {
 	}
 	s.ClearEvents()
 	s.ResetCounters()
+	s.ResetScenarios()
+	s.ResetResources()
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -106,20 +135,50 @@ func (s *Server) ServeRoutes(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	methods := s.Methods()
-	routes := make([]RouteInfo, 0, len(methods))
-	for _, method := range methods {
-		routes = append(routes, RouteInfo{
-			Name:   method.Name,
-			Method: method.Method,
-			Path:   method.Path,
-			Query:  method.Query.Encode(),
-		})
+	s.mu.Lock()
+	methods := s.methods
+	precedence := s.precedence
+	rotations := s.rotationInfo()
+	s.mu.Unlock()
+
+	var routes []RouteInfo
+	if precedence == PrecedenceRotate {
+		routes = make([]RouteInfo, 0, len(methods))
+		for i, method := range methods {
+			route := routeInfo(method)
+			route.Rotations = rotations[i]
+			routes = append(routes, route)
+		}
+	} else {
+		routes = routesFromMethods(methods, rotations)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(routes)
 }
 
+// rotationInfo reports each rotation counter under the index of every route
+// in its group, sorted by scope. The caller must hold s.mu.
+func (s *Server) rotationInfo() map[int][]RotationInfo {
+	info := make(map[int][]RotationInfo)
+	for key, counter := range s.counters {
+		for position, index := range counter.sections {
+			info[index] = append(info[index], RotationInfo{
+				Scope:    key.scope,
+				Position: position + 1,
+				Next:     counter.next() + 1,
+				Size:     len(counter.sections),
+				Count:    counter.count,
+			})
+		}
+	}
+	for _, rotations := range info {
+		slices.SortFunc(rotations, func(a, b RotationInfo) int {
+			return cmp.Or(cmp.Compare(a.Scope, b.Scope), cmp.Compare(a.Size, b.Size), cmp.Compare(a.Count, b.Count))
+		})
+	}
+	return info
+}
+
 func (s *Server) subscribe() ([]RequestEvent, chan RequestEvent) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -206,16 +265,34 @@ func parseLastEventID(r *http.Request) uint64 {
 	return id
 }
 
-// RoutesFromMethods is a helper for tests and CLI summaries.
+// RoutesFromMethods is a helper for tests and CLI summaries. Routes are listed
+// in resolved precedence order, most specific first; ties keep load order.
 func RoutesFromMethods(methods []restclient.Method) []RouteInfo {
+	return routesFromMethods(methods, nil)
+}
+
+func routesFromMethods(methods []restclient.Method, rotations map[int][]RotationInfo) []RouteInfo {
+	order, ranks := precedenceOrder(methods)
 	routes := make([]RouteInfo, 0, len(methods))
-	for _, method := range methods {
-		routes = append(routes, RouteInfo{
-			Name:   method.Name,
-			Method: method.Method,
-			Path:   method.Path,
-			Query:  method.Query.Encode(),
-		})
+	for _, index := range order {
+		route := routeInfo(methods[index])
+		route.Precedence = ranks[index]
+		route.Rotations = rotations[index]
+		routes = append(routes, route)
 	}
 	return routes
 }
+
+func routeInfo(method restclient.Method) RouteInfo {
+	return RouteInfo{
+		Name:               method.Name,
+		Method:             method.Method,
+		Host:               method.Host,
+		Path:               method.Path,
+		Query:              method.Query.Encode(),
+		Variables:          method.Variables,
+		Headers:            method.Headers,
+		InheritedVariables: method.InheritedVariables,
+		InheritedHeaders:   method.InheritedHeaders,
+	}
+}
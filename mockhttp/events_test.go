@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -117,8 +118,82 @@ ok
 	if routes.Code != http.StatusOK {
 		t.Fatalf("routes status = %d, want %d", routes.Code, http.StatusOK)
 	}
-	if body := routes.Body.String(); !strings.Contains(body, `"path":"/users"`) {
-		t.Fatalf("routes body = %q, want /users", body)
+	if body := routes.Body.String(); !strings.Contains(body, `"path":"/users"`) || !strings.Contains(body, `"precedence":1`) {
+		t.Fatalf("routes body = %q, want /users with precedence", body)
+	}
+}
+
+func TestServeRoutesShowsInheritedConfiguration(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`@region = eu
+Content-Type: application/json
+
+### User
+GET /users
+
+{"region":"{{$region}}"}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if got := response.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want inherited application/json", got)
+	}
+	if body := response.Body.String(); body != `{"region":"eu"}` {
+		t.Fatalf("body = %q, want inherited region", body)
+	}
+
+	routes := httptest.NewRecorder()
+	server.ServeRoutes(routes, httptest.NewRequest(http.MethodGet, "/routes", nil))
+	body := routes.Body.String()
+	for _, want := range []string{`"variables":{"region":"eu"}`, `"inheritedVariables":["region"]`, `"inheritedHeaders":["Content-Type"]`} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("routes body = %q, want to contain %q", body, want)
+		}
+	}
+}
+
+func TestServeRoutesReportsRotationPositions(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### First
+# $rotateBy=path
+GET /users/:id
+
+first
+
+### Second
+GET /users/:id
+
+second
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	response := httptest.NewRecorder()
+	server.ServeRoutes(response, httptest.NewRequest(http.MethodGet, "/routes", nil))
+	var routes []RouteInfo
+	if err := json.Unmarshal(response.Body.Bytes(), &routes); err != nil {
+		t.Fatalf("routes JSON: %v", err)
+	}
+	want := []RotationInfo{{Scope: "path /users/1", Position: 1, Next: 2, Size: 2, Count: 1}}
+	if !reflect.DeepEqual(routes[0].Rotations, want) {
+		t.Fatalf("first rotations = %#v, want %#v", routes[0].Rotations, want)
+	}
+	want[0].Position = 2
+	if !reflect.DeepEqual(routes[1].Rotations, want) {
+		t.Fatalf("second rotations = %#v, want %#v", routes[1].Rotations, want)
+	}
+
+	server.ResetCounters()
+	response = httptest.NewRecorder()
+	server.ServeRoutes(response, httptest.NewRequest(http.MethodGet, "/routes", nil))
+	if strings.Contains(response.Body.String(), "rotations") {
+		t.Fatalf("routes after reset = %s, want no rotations", response.Body.String())
 	}
 }
 
@@ -0,0 +1,147 @@
+package mockhttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+// filterPattern matches one "| name" or "| name(args)" step of a placeholder's
+// filter chain.
+var filterPattern = regexp.MustCompile(`\|\s*([A-Za-z][A-Za-z0-9]*)(?:\(([^(){}]*)\))?`)
+
+// applyFilters runs value through a raw filter chain such as
+// "| truncate(20) | upper", left to right.
+func applyFilters(value, chain string) (string, error) {
+	for _, step := range filterPattern.FindAllStringSubmatch(chain, -1) {
+		var err error
+		if value, err = applyFilter(value, step[1], step[2]); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+func applyFilter(value, name, rawArgs string) (string, error) {
+	args := strings.TrimSpace(rawArgs)
+	if name != "truncate" && args != "" {
+		return "", fmt.Errorf("filter %s takes no arguments", name)
+	}
+	switch name {
+	case "json":
+		return jsonString(value), nil
+	case "urlencode":
+		return url.QueryEscape(value), nil
+	case "base64":
+		return base64Encode(value), nil
+	case "sha256":
+		return sha256Hex(value), nil
+	case "upper":
+		return strings.ToUpper(value), nil
+	case "lower":
+		return strings.ToLower(value), nil
+	case "truncate":
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("truncate length %q is not a non-negative integer", args)
+		}
+		return truncate(n, value), nil
+	default:
+		return "", fmt.Errorf("unknown filter %q", name)
+	}
+}
+
+func base64Encode(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate keeps the first n runes of value.
+func truncate(n int, value string) string {
+	runes := []rune(value)
+	if len(runes) <= n {
+		return value
+	}
+	return string(runes[:n])
+}
+
+// jsonString encodes value as a quoted JSON string without HTML escaping.
+func jsonString(value string) string {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// escapeJSONStrings reports whether placeholders in a section's body are
+// escaped inside JSON string literals: $escape=json forces it, $escape=none
+// turns it off, and otherwise it follows a JSON response Content-Type.
+func escapeJSONStrings(method restclient.Method, filePath string) bool {
+	switch method.Variables["escape"] {
+	case "json":
+		return true
+	case "none":
+		return false
+	}
+	return isJSONResponse(method, filePath)
+}
+
+// isJSONResponse reports whether a section's response Content-Type, set as a
+// header or inferred from its $file, is JSON.
+func isJSONResponse(method restclient.Method, filePath string) bool {
+	contentType := method.Headers.Get("Content-Type")
+	if contentType == "" && method.Body == "" && filePath != "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filePath))
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// jsonStringState tracks whether a scan of JSON text is inside a string
+// literal.
+type jsonStringState struct {
+	inString bool
+	escaped  bool
+}
+
+// scan advances the state over literal JSON text.
+func (s *jsonStringState) scan(text string) {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case s.escaped:
+			s.escaped = false
+		case s.inString && text[i] == '\\':
+			s.escaped = true
+		case text[i] == '"':
+			s.inString = !s.inString
+		}
+	}
+}
+
+// parseEscape validates an $escape value.
+func parseEscape(raw string) error {
+	switch raw {
+	case "auto", "json", "none":
+		return nil
+	}
+	return fmt.Errorf(`unknown escape %q (use "auto", "json" or "none")`, raw)
+}
@@ -0,0 +1,136 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+func TestApplyFilters(t *testing.T) {
+	tests := []struct {
+		value string
+		chain string
+		want  string
+	}{
+		{`say "hi"` + "\n", "| json", `"say \"hi\"\n"`},
+		{"<a&b>", "|json", `"<a&b>"`},
+		{"a b&c", "| urlencode", "a+b%26c"},
+		{"hello", "| base64", "aGVsbG8="},
+		{"abc", "| sha256", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"Hello", " | upper", "HELLO"},
+		{"Hello", "| lower", "hello"},
+		{"héllo world", "| truncate(4)", "héll"},
+		{"short", "| truncate( 20 )", "short"},
+		{"Hello world", "| truncate(5) | upper | json", `"HELLO"`},
+		{"same", "", "same"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chain, func(t *testing.T) {
+			got, err := applyFilters(tt.value, tt.chain)
+			if err != nil {
+				t.Fatalf("applyFilters(%q, %q) error = %v", tt.value, tt.chain, err)
+			}
+			if got != tt.want {
+				t.Fatalf("applyFilters(%q, %q) = %q, want %q", tt.value, tt.chain, got, tt.want)
+			}
+		})
+	}
+
+	for _, chain := range []string{"| nope", "| truncate", "| truncate(-1)", "| upper(2)"} {
+		if _, err := applyFilters("x", chain); err == nil {
+			t.Errorf("applyFilters(%q) error = nil, want error", chain)
+		}
+	}
+}
+
+func TestExpandTextEscapesJSONStringsOnly(t *testing.T) {
+	method := restclient.Method{Variables: map[string]string{"quote": `say "hi"` + "\n\\", "count": "3"}}
+	input := `{"text":"<{{$quote}}>","n":{{$count}},"raw":{{$quote | json}},"esc":"a\"{{$quote}}"}`
+
+	got := expandText(input, method, nil, newGenerator(), true)
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+		t.Fatalf("expandText() = %s, not JSON: %v", got, err)
+	}
+	want := map[string]any{"text": "<say \"hi\"\n\\>", "n": 3.0, "raw": "say \"hi\"\n\\", "esc": "a\"say \"hi\"\n\\"}
+	for key, value := range want {
+		if decoded[key] != value {
+			t.Fatalf("%s = %#v, want %#v (body %s)", key, decoded[key], value, got)
+		}
+	}
+
+	if raw := expandText(`"{{$quote}}"`, method, nil, newGenerator(), false); raw != `"say "hi"`+"\n\\\"" {
+		t.Fatalf("unescaped = %q", raw)
+	}
+}
+
+func TestEscapeJSONStrings(t *testing.T) {
+	jsonHeaders := http.Header{"Content-Type": {"application/problem+json; charset=utf-8"}}
+	tests := []struct {
+		name     string
+		method   restclient.Method
+		filePath string
+		want     bool
+	}{
+		{"json header", restclient.Method{Headers: jsonHeaders, Body: "{}"}, "", true},
+		{"text header", restclient.Method{Headers: http.Header{"Content-Type": {"text/plain"}}, Body: "x"}, "", false},
+		{"no header", restclient.Method{Body: "{}"}, "", false},
+		{"json file", restclient.Method{}, "users.json", true},
+		{"opt out", restclient.Method{Headers: jsonHeaders, Body: "{}", Variables: map[string]string{"escape": "none"}}, "", false},
+		{"forced", restclient.Method{Body: "{}", Variables: map[string]string{"escape": "json"}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeJSONStrings(tt.method, tt.filePath); got != tt.want {
+				t.Fatalf("escapeJSONStrings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerEscapesEchoedValuesInJSONBodies(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Echo
+POST /echo
+Content-Type: application/json
+X-Note: {{$request.json.note | truncate(5) | urlencode}}
+
+{"note":"{{$request.json.note}}","short":"{{$request.json.note | truncate(5) | upper}}","lorem":"{{$paragraph}}"}
+
+### Echo raw
+# $escape=none
+POST /raw
+Content-Type: application/json
+
+{"note":"{{$request.json.note}}"}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	note := "line \"one\"\nline two"
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"note":`+jsonString(note)+`}`)))
+	var decoded map[string]string
+	if err := json.Unmarshal(response.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("body %s is not JSON: %v", response.Body.String(), err)
+	}
+	if decoded["note"] != note || decoded["short"] != "LINE " || decoded["lorem"] == "" {
+		t.Fatalf("decoded = %#v", decoded)
+	}
+	if got := response.Header().Get("X-Note"); got != "line+" {
+		t.Fatalf("X-Note = %q, want line+", got)
+	}
+
+	response = httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/raw", strings.NewReader(`{"note":`+jsonString(note)+`}`)))
+	if got := response.Body.String(); got != `{"note":"`+note+`"}` {
+		t.Fatalf("$escape=none body = %q", got)
+	}
+}
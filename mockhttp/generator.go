@@ -0,0 +1,398 @@
+package mockhttp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sspencer/mock/restclient"
+
+	"github.com/jaswdr/faker"
+)
+
+// seedClock is the "now" of a seeded generator, so timestamps and dates
+// repeat between runs as well as names and numbers.
+var seedClock = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// generator produces fake values from a single random stream. Both its own
+// draws and faker's read from that stream, so a seeded generator repeats every
+// value in order.
+//
+// Named instances ({{$uuid:order}}) are generated once per generator and then
+// repeated. When stable is set they are instead derived from instanceBase,
+// the instance and instanceScope, so they also repeat across requests.
+type generator struct {
+	rand    *rand.Rand
+	faker   faker.Faker
+	now     time.Time
+	request *http.Request   // passed to registered generators
+	content *requestContent // read by {{$request.body}} and {{$request.json.*}}
+
+	instances     map[string]string
+	stable        bool
+	instanceBase  uint64
+	instanceScope string
+}
+
+// newGenerator returns an unseeded generator that uses the current time.
+func newGenerator() *generator {
+	g := newSeededGenerator(rand.Uint64())
+	g.now = time.Now()
+	return g
+}
+
+// newSeededGenerator returns a generator whose values depend only on seed.
+func newSeededGenerator(seed uint64) *generator {
+	source := &pcgSource{rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)}
+	return &generator{
+		rand:  rand.New(source.pcg),
+		faker: faker.NewWithSeed(source),
+		now:   seedClock,
+	}
+}
+
+// pcgSource adapts a math/rand/v2 PCG to the math/rand Source faker expects.
+type pcgSource struct {
+	pcg *rand.PCG
+}
+
+func (s *pcgSource) Int63() int64 {
+	return int64(s.pcg.Uint64() >> 1)
+}
+
+func (s *pcgSource) Seed(seed int64) {
+	s.pcg.Seed(uint64(seed), uint64(seed)^0x9e3779b97f4a7c15)
+}
+
+// uuid returns a version 4 UUID drawn from the generator's stream; faker's own
+// V4 reads crypto/rand and could never be reproduced.
+func (g *generator) uuid() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], g.rand.Uint64())
+	binary.BigEndian.PutUint64(b[8:], g.rand.Uint64())
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// generatorNames lists the keys generate understands.
+var generatorNames = []string{
+	"integer", "float", "bool", "uuid", "guid", "timestamp", "isoTimestamp",
+	"date", "name", "firstName", "lastName", "phone", "user", "email", "url",
+	"server", "hash", "file", "sentence", "paragraph", "article", "oneOf",
+}
+
+// instance returns the value of the named instance of a generator, generating
+// it on first use.
+func (g *generator) instance(name, key string, args ...string) string {
+	id := key + "(" + strings.Join(args, ",") + "):" + name
+	if value, ok := g.instances[id]; ok {
+		return value
+	}
+	source := g
+	if g.stable {
+		source = newSeededGenerator(seedHash(g.instanceBase, id, g.instanceScope))
+		source.request = g.request
+	}
+	value := source.value(key, args...)
+	if g.instances == nil {
+		g.instances = make(map[string]string)
+	}
+	g.instances[id] = value
+	return value
+}
+
+// value returns a generated value, or "" for an unknown key or invalid
+// arguments.
+func (g *generator) value(key string, args ...string) string {
+	value, _ := g.generate(key, args)
+	return value
+}
+
+// generate renders the generator key with optional arguments:
+//
+//	integer(min,max)            inclusive range
+//	float(min,max[,decimals])   decimals default to 2
+//	sentence(words)             paragraph(sentences)  article(sentences)
+//	oneOf(a,b,...)              one of the arguments
+//	date([from,to][,layout])    from/to as 2006-01-02 or RFC 3339; layout defaults to 2006-01-02
+func (g *generator) generate(key string, args []string) (string, error) {
+	f := g.faker
+
+	switch key {
+	case "integer":
+		if len(args) == 0 {
+			return fmt.Sprint(f.UInt16()), nil
+		}
+		low, high, err := intRange(args)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(low + int(g.randomUpTo(uint64(high)-uint64(low)))), nil
+	case "float":
+		if len(args) == 0 {
+			return fmt.Sprint(f.Float32(2, 0, 100_000)), nil
+		}
+		if len(args) != 2 && len(args) != 3 {
+			return "", fmt.Errorf("float takes (min,max) or (min,max,decimals)")
+		}
+		low, errLow := strconv.ParseFloat(args[0], 64)
+		high, errHigh := strconv.ParseFloat(args[1], 64)
+		if errLow != nil || errHigh != nil || low > high {
+			return "", fmt.Errorf("float range %s,%s is not min,max", args[0], args[1])
+		}
+		decimals := 2
+		if len(args) == 3 {
+			var err error
+			if decimals, err = strconv.Atoi(args[2]); err != nil || decimals < 0 {
+				return "", fmt.Errorf("float decimals %q is not a non-negative integer", args[2])
+			}
+		}
+		return strconv.FormatFloat(low+g.rand.Float64()*(high-low), 'f', decimals, 64), nil
+	case "bool":
+		return fmt.Sprint(f.Boolean().Bool()), noArgs(key, args)
+	case "uuid", "guid":
+		return g.uuid(), noArgs(key, args)
+	case "timestamp":
+		return fmt.Sprint(f.Time().Unix(g.now)), noArgs(key, args)
+	case "isoTimestamp":
+		return f.Time().ISO8601(g.now), noArgs(key, args)
+	case "date":
+		return g.randomDate(args)
+	case "name":
+		return f.Person().Name(), noArgs(key, args)
+	case "firstName":
+		return f.Person().FirstName(), noArgs(key, args)
+	case "lastName":
+		return f.Person().LastName(), noArgs(key, args)
+	case "phone":
+		return f.Phone().Number(), noArgs(key, args)
+	case "user":
+		return f.Internet().User(), noArgs(key, args)
+	case "email":
+		return f.Internet().Email(), noArgs(key, args)
+	case "url":
+		return f.Internet().URL(), noArgs(key, args)
+	case "server":
+		return f.Internet().Domain(), noArgs(key, args)
+	case "hash":
+		return f.Hash().MD5(), noArgs(key, args)
+	case "file":
+		return f.File().AbsoluteFilePath(3 + g.rand.IntN(4)), noArgs(key, args)
+	case "sentence":
+		words, err := countArg(key, args, 8+g.rand.IntN(9))
+		if err != nil {
+			return "", err
+		}
+		return f.Lorem().Sentence(words), nil
+	case "paragraph":
+		sentences, err := countArg(key, args, 3+g.rand.IntN(2))
+		if err != nil {
+			return "", err
+		}
+		return f.Lorem().Paragraph(sentences), nil
+	case "article":
+		sentences, err := countArg(key, args, 5+g.rand.IntN(3))
+		if err != nil {
+			return "", err
+		}
+		return f.Lorem().Paragraph(sentences), nil
+	case "oneOf":
+		if len(args) == 0 {
+			return "", fmt.Errorf("oneOf needs at least one argument")
+		}
+		return args[g.rand.IntN(len(args))], nil
+	default:
+		if fn, ok := customGenerator(key); ok {
+			return fn(args, g.request), nil
+		}
+		return "", fmt.Errorf("unknown generator %q", key)
+	}
+}
+
+func noArgs(key string, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%s takes no arguments", key)
+	}
+	return nil
+}
+
+// countArg returns the single positive count argument, or fallback without one.
+func countArg(key string, args []string, fallback int) (int, error) {
+	switch len(args) {
+	case 0:
+		return fallback, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%s count %q is not a positive integer", key, args[0])
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("%s takes one count argument", key)
+	}
+}
+
+func intRange(args []string) (int, int, error) {
+	if len(args) != 2 {
+		return 0, 0, fmt.Errorf("integer takes (min,max)")
+	}
+	low, errLow := strconv.Atoi(args[0])
+	high, errHigh := strconv.Atoi(args[1])
+	if errLow != nil || errHigh != nil || low > high {
+		return 0, 0, fmt.Errorf("integer range %s,%s is not min,max", args[0], args[1])
+	}
+	return low, high, nil
+}
+
+// randomDate picks a time between from and to (the past year by default)
+// and formats it with layout.
+func (g *generator) randomDate(args []string) (string, error) {
+	to := g.now
+	from := to.AddDate(-1, 0, 0)
+	layout := time.DateOnly
+	switch len(args) {
+	case 0:
+	case 1:
+		layout = args[0]
+	case 2, 3:
+		var err error
+		if from, err = parseDateArg(args[0]); err != nil {
+			return "", err
+		}
+		if to, err = parseDateArg(args[1]); err != nil {
+			return "", err
+		}
+		if to.Before(from) {
+			return "", fmt.Errorf("date range %s,%s ends before it starts", args[0], args[1])
+		}
+		if len(args) == 3 {
+			layout = args[2]
+		}
+	default:
+		return "", fmt.Errorf("date takes ([from,to][,layout])")
+	}
+	// Sub saturates at about 292 years; wider ranges pick whole seconds.
+	picked := from
+	if span := to.Sub(from); span == math.MaxInt64 {
+		seconds := g.randomUpTo(uint64(to.Unix() - from.Unix()))
+		picked = time.Unix(from.Unix()+int64(seconds), 0).In(from.Location())
+	} else if span > 0 {
+		picked = from.Add(time.Duration(g.randomUpTo(uint64(span))))
+	}
+	return picked.Format(layout), nil
+}
+
+// randomUpTo returns a random value in [0, n], inclusive, covering the full
+// uint64 range when n is math.MaxUint64.
+func (g *generator) randomUpTo(n uint64) uint64 {
+	if n == math.MaxUint64 {
+		return g.rand.Uint64()
+	}
+	return g.rand.Uint64N(n + 1)
+}
+
+func parseDateArg(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q is not 2006-01-02 or RFC 3339", raw)
+	}
+	return t, nil
+}
+
+// parseSeed validates a $seed value: a 64-bit integer.
+func parseSeed(raw string) (uint64, error) {
+	seed, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("seed %q is not an integer", raw)
+	}
+	return uint64(seed), nil
+}
+
+// parseSeedBy validates a $seedBy value: "none", "path", "param:name",
+// "query:name" or "header:Name".
+func parseSeedBy(raw string) (string, error) {
+	switch raw {
+	case "none", "path":
+		return raw, nil
+	}
+	for _, prefix := range []string{"param:", "query:", "header:"} {
+		if name, ok := strings.CutPrefix(raw, prefix); ok && strings.TrimSpace(name) != "" {
+			return raw, nil
+		}
+	}
+	return "", fmt.Errorf(`unknown seedBy %q (use "none", "path", "param:name", "query:name" or "header:Name")`, raw)
+}
+
+// responseGenerator returns the generator for one response. Without a seed
+// (-seed, $seed or $seedBy) values are random. Otherwise the seed mixes the
+// base seed, the section name and the request data chosen by $seedBy (the
+// request path by default), so the same request always renders the same
+// values whatever order requests arrive in.
+func (s *Server) responseGenerator(r *http.Request, method *restclient.Method, values map[string]string, content *requestContent) *generator {
+	s.mu.Lock()
+	base, seeded := s.seed, s.seeded
+	s.mu.Unlock()
+
+	if raw, ok := method.Variables["seed"]; ok {
+		if seed, err := parseSeed(raw); err == nil {
+			base, seeded = seed, true
+		}
+	}
+	seedBy := "path"
+	if raw, ok := method.Variables["seedBy"]; ok {
+		if parsed, err := parseSeedBy(raw); err == nil {
+			seedBy, seeded = parsed, true
+		}
+	}
+	gen := newGenerator()
+	if seeded {
+		gen = newSeededGenerator(seedHash(base, method.Name, seedScope(r, seedBy, values)))
+	}
+	gen.request = r
+	gen.content = content
+	if raw, ok := method.Variables["instanceBy"]; ok {
+		if instanceBy, err := parseSeedBy(raw); err == nil && instanceBy != "none" {
+			gen.stable = true
+			gen.instanceBase = base
+			gen.instanceScope = instanceBy + " " + seedScope(r, instanceBy, values)
+		}
+	}
+	return gen
+}
+
+// seedHash mixes a base seed with strings into a generator seed.
+func seedHash(base uint64, parts ...string) uint64 {
+	hash := fnv.New64a()
+	_ = binary.Write(hash, binary.BigEndian, base)
+	for _, part := range parts {
+		fmt.Fprintf(hash, "\x00%s", part)
+	}
+	return hash.Sum64()
+}
+
+// seedScope returns the request data a $seedBy value selects.
+func seedScope(r *http.Request, seedBy string, values map[string]string) string {
+	if seedBy == "path" {
+		return r.URL.Path
+	}
+	if name, ok := strings.CutPrefix(seedBy, "param:"); ok {
+		return values[strings.TrimSpace(name)]
+	}
+	if name, ok := strings.CutPrefix(seedBy, "query:"); ok {
+		return r.URL.Query().Get(strings.TrimSpace(name))
+	}
+	if name, ok := strings.CutPrefix(seedBy, "header:"); ok {
+		return r.Header.Get(strings.TrimSpace(name))
+	}
+	return ""
+}
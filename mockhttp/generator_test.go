@@ -0,0 +1,226 @@
+package mockhttp
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+func TestGeneratedValueArguments(t *testing.T) {
+	tests := []struct {
+		key   string
+		args  []string
+		check func(string) bool
+	}{
+		{"integer", []string{"1", "3"}, func(v string) bool { n, err := strconv.Atoi(v); return err == nil && n >= 1 && n <= 3 }},
+		{"integer", []string{"-5", "-5"}, func(v string) bool { return v == "-5" }},
+		{"integer", []string{"0", "9223372036854775807"}, func(v string) bool { n, err := strconv.ParseInt(v, 10, 64); return err == nil && n >= 0 }},
+		{"integer", []string{"-9223372036854775808", "9223372036854775807"}, func(v string) bool { _, err := strconv.ParseInt(v, 10, 64); return err == nil }},
+		{"float", []string{"0", "1", "4"}, func(v string) bool {
+			n, err := strconv.ParseFloat(v, 64)
+			return err == nil && n >= 0 && n <= 1 && len(v) == 6
+		}},
+		{"sentence", []string{"5"}, func(v string) bool { return len(strings.Fields(v)) == 5 }},
+		{"oneOf", []string{"red", "green", "blue"}, func(v string) bool { return slices.Contains([]string{"red", "green", "blue"}, v) }},
+		{"date", []string{"2024-01-01", "2024-12-31", "2006-01-02"}, func(v string) bool {
+			d, err := time.Parse(time.DateOnly, v)
+			return err == nil && d.Year() == 2024
+		}},
+		{"date", []string{"2024-03-05", "2024-03-05", "Jan 2 2006"}, func(v string) bool { return v == "Mar 5 2024" }},
+		{"date", []string{"0001-01-01", "9999-12-31"}, func(v string) bool {
+			_, err := time.Parse(time.DateOnly, v)
+			return err == nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"("+strings.Join(tt.args, ",")+")", func(t *testing.T) {
+			if got := newGenerator().value(tt.key, tt.args...); !tt.check(got) {
+				t.Fatalf("value(%q, %q) = %q", tt.key, tt.args, got)
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsInvalidArguments(t *testing.T) {
+	tests := []struct {
+		key  string
+		args []string
+	}{
+		{"integer", []string{"1"}},
+		{"integer", []string{"9", "1"}},
+		{"integer", []string{"a", "b"}},
+		{"float", []string{"0", "1", "-1"}},
+		{"sentence", []string{"0"}},
+		{"oneOf", nil},
+		{"date", []string{"2024-12-31", "2024-01-01"}},
+		{"date", []string{"yesterday", "today"}},
+		{"uuid", []string{"4"}},
+		{"missing", []string{"1"}},
+	}
+	for _, tt := range tests {
+		if _, err := newGenerator().generate(tt.key, tt.args); err == nil {
+			t.Errorf("generate(%q, %q) error = nil, want error", tt.key, tt.args)
+		}
+	}
+}
+
+func TestSeededGeneratorRepeatsValues(t *testing.T) {
+	render := func(seed uint64) []string {
+		g := newSeededGenerator(seed)
+		var values []string
+		for _, key := range generatorNames {
+			if key != "oneOf" {
+				values = append(values, g.value(key))
+			}
+		}
+		return values
+	}
+	first, second := render(7), render(7)
+	if !slices.Equal(first, second) {
+		t.Fatalf("seed 7 rendered %q then %q", first, second)
+	}
+	if slices.Equal(first, render(8)) {
+		t.Fatalf("seeds 7 and 8 rendered the same values %q", first)
+	}
+}
+
+func TestServerSeedsGeneratedValues(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### User
+GET /users/:id
+X-Trace: {{$uuid}}
+
+{"id":"{{$id}}","name":"{{$name}}","at":"{{$isoTimestamp}}"}
+
+### Template user
+# $template=go
+GET /template/:id
+
+{{name}} {{integer 1 1000000}}
+
+### Section seed
+# $seed=99
+# $seedBy=param:team
+GET /teams/:team/members/:id
+
+{{$name}}
+
+### Header seed
+# $seedBy=header:X-Client
+GET /clients
+
+{{$uuid}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	newServer := func(seed int64, seeded bool) *Server {
+		server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if seeded {
+			server.SetSeed(seed)
+		}
+		return server
+	}
+	serve := func(server *Server, target string, header ...string) string {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		if len(header) == 2 {
+			request.Header.Set(header[0], header[1])
+		}
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response.Header().Get("X-Trace") + " " + response.Body.String()
+	}
+
+	seeded, rerun := newServer(42, true), newServer(42, true)
+	for _, target := range []string{"/users/42", "/template/42"} {
+		first := serve(seeded, target)
+		if again := serve(seeded, target); again != first {
+			t.Fatalf("%s rendered %q then %q", target, first, again)
+		}
+		if other := serve(rerun, target); other != first {
+			t.Fatalf("%s rendered %q, new server with the same seed %q", target, first, other)
+		}
+	}
+	if serve(seeded, "/users/42") == serve(seeded, "/users/43") {
+		t.Fatal("/users/42 and /users/43 rendered the same values")
+	}
+	if serve(seeded, "/users/42") == serve(newServer(43, true), "/users/42") {
+		t.Fatal("seeds 42 and 43 rendered the same values")
+	}
+	if serve(newServer(0, false), "/users/42") == serve(newServer(0, false), "/users/42") {
+		t.Fatal("unseeded servers rendered the same values")
+	}
+
+	unseeded := newServer(0, false)
+	if serve(unseeded, "/teams/a/members/1") != serve(unseeded, "/teams/a/members/2") {
+		t.Fatal("$seedBy=param:team rendered different values for the same team")
+	}
+	if serve(unseeded, "/teams/a/members/1") == serve(unseeded, "/teams/b/members/1") {
+		t.Fatal("$seedBy=param:team rendered the same values for different teams")
+	}
+	if serve(unseeded, "/clients", "X-Client", "a") != serve(newServer(0, false), "/clients", "X-Client", "a") {
+		t.Fatal("$seedBy=header:X-Client rendered different values for the same client")
+	}
+	if serve(unseeded, "/clients", "X-Client", "a") == serve(unseeded, "/clients", "X-Client", "b") {
+		t.Fatal("$seedBy=header:X-Client rendered the same values for different clients")
+	}
+}
+
+func TestServerNamedInstancesAgreeAcrossHeadersAndRequests(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Create order
+# $status=201
+POST /orders
+Location: /orders/{{$uuid:order}}
+
+{"id":"{{$uuid:order}}"}
+
+### Customer order
+# $instanceBy=param:customer
+POST /customers/:customer/orders
+Location: /orders/{{$uuid:order}}
+
+{"id":"{{$uuid:order}}","ref":"{{$integer(1,1000000):ref}}"}
+
+### Customer latest
+# $instanceBy=param:customer
+GET /customers/:customer/latest
+
+{"id":"{{$uuid:order}}","ref":"{{$integer(1,1000000):ref}}"}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	serve := func(method, target string) (string, string) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(method, target, nil))
+		return response.Header().Get("Location"), response.Body.String()
+	}
+
+	location, body := serve(http.MethodPost, "/orders")
+	id := strings.TrimPrefix(location, "/orders/")
+	if len(id) != 36 || body != `{"id":"`+id+`"}` {
+		t.Fatalf("Location = %q, body = %q, want the same id", location, body)
+	}
+	if next, _ := serve(http.MethodPost, "/orders"); next == location {
+		t.Fatalf("second create repeated %q without $instanceBy", location)
+	}
+
+	location, created := serve(http.MethodPost, "/customers/7/orders")
+	if !strings.Contains(created, strings.TrimPrefix(location, "/orders/")) {
+		t.Fatalf("Location = %q, body = %q, want the same id", location, created)
+	}
+	if _, latest := serve(http.MethodGet, "/customers/7/latest"); latest != created {
+		t.Fatalf("latest = %q, want %q", latest, created)
+	}
+	if _, other := serve(http.MethodGet, "/customers/8/latest"); other == created {
+		t.Fatalf("customer 8 repeated customer 7's order %q", other)
+	}
+}
@@ -1,97 +1,376 @@
 package mockhttp
 
 import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/sspencer/mock/restclient"
 )
 
-func (s *Server) findMethod(r *http.Request) (*restclient.Method, map[string]string, bool) {
-	type match struct {
-		method *restclient.Method
-		values map[string]string
-	}
+// routeMatch is a candidate route for a request with its path and query values.
+// index is the route's position in the server's methods slice.
+type routeMatch struct {
+	method *restclient.Method
+	index  int
+	values map[string]string
+}
 
+// findMethod selects the mock for r. body is the request body already captured
+// by readRequestBody; body matchers read it instead of consuming r.Body. A HEAD
+// request with no HEAD or ANY section falls back to the GET sections. The
+// returned requestContent is shared with response rendering, so the body is
+// decoded at most once per request.
+func (s *Server) findMethod(r *http.Request, body loggedBody) (*restclient.Method, map[string]string, *requestContent, bool) {
 	// Snapshot the methods slice under the lock so hot-reload via SetMethods
 	// cannot race with matching. Pointers into the snapshot remain valid for
 	// this request even after a later SetMethods replaces s.methods.
 	s.mu.Lock()
 	methods := s.methods
+	precedence := s.precedence
+	states := maps.Clone(s.scenarios)
 	s.mu.Unlock()
+	logger := s.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	request := &requestInfo{
+		states:  states,
+		query:   r.URL.Query(),
+		cookies: cookieValues(r),
+		content: &requestContent{body: body, contentType: r.Header.Get("Content-Type"), request: r, logger: logger},
+	}
+	matches := matchRoutes(methods, r, r.Method, request)
+	if len(matches) == 0 && r.Method == http.MethodHead {
+		matches = matchRoutes(methods, r, http.MethodGet, request)
+	}
+	selected, ok := s.nextMatch(r, matches, precedence)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	s.advanceScenario(selected.method)
+	return selected.method, selected.values, request.content, true
+}
+
+// requestValue resolves a request.* placeholder key: method, path, body,
+// header.<Name>, query.<name> (its first value), and json.<path> for a field
+// of a JSON body. The body is only read and decoded when a placeholder asks
+// for it. JSON strings render as-is; other JSON values render as compact JSON.
+func requestValue(key string, r *http.Request, content *requestContent) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(key, "request.")
+	if !ok {
+		return "", false
+	}
+	switch name {
+	case "method":
+		return r.Method, true
+	case "path":
+		return r.URL.Path, true
+	case "body":
+		if content == nil {
+			return "", false
+		}
+		return content.text()
+	}
+	if header, ok := strings.CutPrefix(name, "header."); ok {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	}
+	if query, ok := strings.CutPrefix(name, "query."); ok {
+		values := r.URL.Query()[query]
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	}
+	if content == nil || (name != "json" && !strings.HasPrefix(name, "json.")) {
+		return "", false
+	}
+	value, ok := content.jsonValue()
+	if path, isField := strings.CutPrefix(name, "json."); ok && isField {
+		value, ok = lookupJSONPath(value, path)
+	}
+	if !ok {
+		return "", false
+	}
+	if text, isString := value.(string); isString {
+		return text, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// requestInfo holds request values decoded once and shared by every candidate,
+// and the scenario states when matching started.
+type requestInfo struct {
+	states  map[string]string
+	query   url.Values
+	cookies map[string][]string
+	content *requestContent
+}
 
-	var matches []match
+// matchRoutes returns the routes that accept r when it is treated as verb.
+func matchRoutes(methods []restclient.Method, r *http.Request, verb string, request *requestInfo) []routeMatch {
+	var matches []routeMatch
 	for i := range methods {
 		method := &methods[i]
-		if method.Method != r.Method {
+		if method.Method != verb && method.Method != restclient.MethodAny && !isResource(method) {
+			continue
+		}
+		if !hostMatches(method.Host, r.Host) || !scenarioAllows(method, request.states) {
+			continue
+		}
+		values, ok := matchSectionPath(method, r.URL.Path)
+		if !ok || !queryMatches(method.Query, request.query) {
+			continue
+		}
+		if !queryMatchesAll(method.MatchQuery, request.query) || !headerMatches(method.MatchHeaders, r.Header) ||
+			!cookieMatches(method.MatchCookies, request.cookies) {
 			continue
 		}
-		values, ok := matchPath(method.Path, r.URL.Path)
-		if !ok || !queryMatches(method.Query, r.URL.Query()) {
+		if !jsonMatches(method.MatchJSON, request.content) {
 			continue
 		}
-		if !headerMatches(method.MatchHeaders, r.Header) {
+		if !formMatches(method.MatchForm, request.content) {
 			continue
 		}
-		for name, queryValues := range r.URL.Query() {
+		for name, queryValues := range request.query {
 			if len(queryValues) > 0 {
 				values[name] = queryValues[0]
 			}
 		}
-		matches = append(matches, match{method: method, values: values})
-	}
-	if len(matches) == 0 {
-		return nil, nil, false
+		for name, cookieValues := range request.cookies {
+			values["cookie."+name] = cookieValues[0]
+		}
+		matches = append(matches, routeMatch{method: method, index: i, values: values})
 	}
+	return matches
+}
+
+// anyMethodAllows lists the methods an ANY section answers in an Allow header.
+var anyMethodAllows = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// allowedMethods returns the methods of the routes whose host and path match
+// r, for an Allow header. GET implies HEAD, and OPTIONS is always included once
+// any route matches. It returns nil when no path matches.
+func (s *Server) allowedMethods(r *http.Request) []string {
+	s.mu.Lock()
+	methods := s.methods
+	s.mu.Unlock()
 
-	selected := s.nextMatch(r, len(matches))
-	return matches[selected].method, matches[selected].values, true
+	allowed := make(map[string]bool)
+	for _, method := range methods {
+		if !hostMatches(method.Host, r.Host) {
+			continue
+		}
+		if _, ok := matchSectionPath(&method, r.URL.Path); !ok {
+			continue
+		}
+		switch method.Method {
+		case restclient.MethodAny:
+			for _, verb := range anyMethodAllows {
+				allowed[verb] = true
+			}
+		case http.MethodGet:
+			allowed[http.MethodGet] = true
+			allowed[http.MethodHead] = true
+		default:
+			allowed[method.Method] = true
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	allowed[http.MethodOptions] = true
+	verbs := slices.Collect(maps.Keys(allowed))
+	slices.SortFunc(verbs, func(a, b string) int {
+		return cmp.Or(cmp.Compare(methodOrder(a), methodOrder(b)), cmp.Compare(a, b))
+	})
+	return verbs
 }
 
-func (s *Server) nextMatch(r *http.Request, count int) int {
-	if count == 1 {
-		return 0
+// methodOrder sorts Allow header methods in the conventional order, with
+// methods outside anyMethodAllows last.
+func methodOrder(verb string) int {
+	if i := slices.Index(anyMethodAllows, verb); i >= 0 {
+		return i
 	}
-	key := r.Method + " " + r.URL.RequestURI()
+	return len(anyMethodAllows)
+}
+
+// nextMatch drops $strategy=once sections that were already served, narrows
+// the rest to the most specific under precedence, and picks one using the
+// group's $strategy, marking a selected $strategy=once section consumed. It
+// holds s.mu throughout, so concurrent requests cannot both take a once
+// section. Counters belong to the candidate group, further split by the
+// group's $rotateBy.
+func (s *Server) nextMatch(r *http.Request, matches []routeMatch, precedence Precedence) (routeMatch, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	selected := s.counters[key] % count
-	s.counters[key]++
-	return selected
+	matches = slices.DeleteFunc(matches, func(match routeMatch) bool {
+		return s.consumed[match.method]
+	})
+	if len(matches) == 0 {
+		return routeMatch{}, false
+	}
+	if precedence != PrecedenceRotate {
+		matches = mostSpecific(matches)
+	}
+	strategy := groupStrategy(matches)
+	if len(matches) == 1 && strategy != StrategyOnce {
+		return matches[0], true
+	}
+
+	var selected int
+	switch strategy {
+	case StrategyRandom:
+		selected = rand.IntN(len(matches))
+	case StrategyWeighted:
+		selected = weightedIndex(matches)
+	case StrategyOnce:
+		selected = max(slices.IndexFunc(matches, isOnce), 0)
+	default:
+		counter := s.rotation(r, matches)
+		counter.stick = strategy == StrategySequenceThenStick
+		selected = counter.next()
+		counter.count++
+	}
+	if isOnce(matches[selected]) {
+		s.consumed[matches[selected].method] = true
+	}
+	return matches[selected], true
+}
+
+// rotation returns the counter for a candidate group, creating it on first
+// use. The caller must hold s.mu.
+func (s *Server) rotation(r *http.Request, matches []routeMatch) *rotation {
+	sections := make([]int, len(matches))
+	for i, match := range matches {
+		sections[i] = match.index
+	}
+	key := rotationKey{group: fmt.Sprint(sections), scope: rotationScope(r, matches)}
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &rotation{sections: sections}
+		s.counters[key] = counter
+	}
+	return counter
+}
+
+func isOnce(match routeMatch) bool {
+	strategy, ok := methodStrategy(match.method)
+	return ok && strategy == StrategyOnce
+}
+
+// hostMatches reports whether requestHost (an r.Host value, possibly with a
+// port) satisfies a route host. An empty route host matches any request, and
+// "*.example.test" matches any subdomain of example.test but not the apex.
+func hostMatches(pattern, requestHost string) bool {
+	if pattern == "" {
+		return true
+	}
+	host := strings.ToLower(requestHost)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.TrimSuffix(host, ".")
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
 }
 
 func matchPath(pattern string, requestPath string) (map[string]string, bool) {
 	if strings.HasSuffix(pattern, "/") && strings.TrimSuffix(requestPath, "index.html") == pattern {
 		requestPath = pattern
 	}
-	patternParts := splitPath(pattern)
+	patternParts, ok := pathSegments(pattern)
+	if !ok {
+		return nil, false
+	}
 	requestParts := splitPath(requestPath)
-	if len(patternParts) != len(requestParts) {
+	catchAll := len(patternParts) > 0 && patternParts[len(patternParts)-1].Kind == restclient.SegmentCatchAll
+	if catchAll && len(requestParts) < len(patternParts)-1 ||
+		!catchAll && len(patternParts) != len(requestParts) {
 		return nil, false
 	}
 
 	values := make(map[string]string)
-	for i := range patternParts {
-		if key, ok := strings.CutPrefix(patternParts[i], ":"); ok {
-			if key == "" {
+	for i, segment := range patternParts {
+		switch segment.Kind {
+		case restclient.SegmentLiteral:
+			if segment.Literal != requestParts[i] {
 				return nil, false
 			}
-			value, err := url.PathUnescape(requestParts[i])
+			continue
+		case restclient.SegmentWildcard:
+			continue
+		case restclient.SegmentCatchAll:
+			rest, err := url.PathUnescape(strings.Join(requestParts[i:], "/"))
 			if err != nil {
 				return nil, false
 			}
-			values[key] = value
+			values[segment.Name] = rest
 			continue
 		}
-		if patternParts[i] != requestParts[i] {
+		value, err := url.PathUnescape(requestParts[i])
+		if err != nil {
+			return nil, false
+		}
+		if segment.Pattern != nil && !segment.Pattern.MatchString(value) {
 			return nil, false
 		}
+		values[segment.Name] = value
 	}
 	return values, true
 }
 
+// parsedPaths caches route path patterns so regular expressions compile once.
+// Entries hold []restclient.PathSegment, or nil when the pattern is invalid.
+var parsedPaths sync.Map
+
+func pathSegments(pattern string) ([]restclient.PathSegment, bool) {
+	if cached, ok := parsedPaths.Load(pattern); ok {
+		segments, _ := cached.([]restclient.PathSegment)
+		return segments, cached != nil
+	}
+	segments, err := restclient.ParsePath(pattern)
+	if err != nil {
+		parsedPaths.Store(pattern, nil)
+		return nil, false
+	}
+	parsedPaths.Store(pattern, segments)
+	return segments, true
+}
+
 func splitPath(path string) []string {
 	trimmed := strings.Trim(path, "/")
 	if trimmed == "" {
@@ -115,22 +394,52 @@ func queryMatches(expected url.Values, actual url.Values) bool {
 	return true
 }
 
-// headerMatches requires every expected header name to be present with a matching
-// value. Expected value "*" matches any non-empty request header value.
+// queryMatchesAll requires every $query. matcher to accept the request's
+// values for that parameter.
+func queryMatchesAll(expected map[string]string, actual url.Values) bool {
+	for name, raw := range expected {
+		values, present := actual[name]
+		if !valueMatches(raw, values, present) {
+			return false
+		}
+	}
+	return true
+}
+
+// cookieValues groups the request's cookies by name, in header order.
+func cookieValues(r *http.Request) map[string][]string {
+	cookies := make(map[string][]string)
+	for _, cookie := range r.Cookies() {
+		cookies[cookie.Name] = append(cookies[cookie.Name], cookie.Value)
+	}
+	return cookies
+}
+
+// cookieMatches requires every $cookie. matcher to accept the request's
+// cookies of that name. "*" requires a non-empty cookie value.
+func cookieMatches(expected map[string]string, actual map[string][]string) bool {
+	for name, raw := range expected {
+		values := actual[name]
+		present := len(values) > 0 && (raw != "*" || values[0] != "")
+		if !valueMatches(raw, values, present) {
+			return false
+		}
+	}
+	return true
+}
+
+// headerMatches requires every expected header to satisfy its matcher. Expected
+// value "*" matches any non-empty request header value, "~regex" any value the
+// pattern matches, and "!" a missing header.
 func headerMatches(expected http.Header, actual http.Header) bool {
 	for name, expectedValues := range expected {
 		actualValues := actual.Values(name)
-		if len(actualValues) == 0 {
-			return false
-		}
 		for _, expectedValue := range expectedValues {
+			present := len(actualValues) > 0
 			if expectedValue == "*" {
-				if strings.TrimSpace(actualValues[0]) == "" {
-					return false
-				}
-				continue
+				present = present && strings.TrimSpace(actualValues[0]) != ""
 			}
-			if !headerValuePresent(actualValues, expectedValue) {
+			if !valueMatches(expectedValue, actualValues, present) {
 				return false
 			}
 		}
@@ -138,11 +447,307 @@ func headerMatches(expected http.Header, actual http.Header) bool {
 	return true
 }
 
-func headerValuePresent(actual []string, want string) bool {
-	for _, value := range actual {
-		if value == want {
+// valueMatches applies the $query./$header. matcher raw to the request values
+// for one name; present reports whether the name counts as supplied.
+func valueMatches(raw string, values []string, present bool) bool {
+	matcher, ok := valueMatcher(raw)
+	if !ok {
+		return false
+	}
+	switch matcher.Kind {
+	case restclient.MatchAbsent:
+		return !present
+	case restclient.MatchPresent:
+		return present
+	case restclient.MatchPattern:
+		return slices.ContainsFunc(values, matcher.Pattern.MatchString)
+	default:
+		return slices.Contains(values, matcher.Value)
+	}
+}
+
+// parsedMatchers caches matcher values so regular expressions compile once.
+// Entries hold restclient.ValueMatcher, or nil when the pattern is invalid.
+var parsedMatchers sync.Map
+
+func valueMatcher(raw string) (restclient.ValueMatcher, bool) {
+	if cached, ok := parsedMatchers.Load(raw); ok {
+		matcher, ok := cached.(restclient.ValueMatcher)
+		return matcher, ok
+	}
+	matcher, err := restclient.ParseValueMatcher(raw)
+	if err != nil {
+		parsedMatchers.Store(raw, nil)
+		return matcher, false
+	}
+	parsedMatchers.Store(raw, matcher)
+	return matcher, true
+}
+
+// maxMatchBodyBytes bounds how much of a request body is read for body
+// matchers. Bodies up to maxLoggedBodyBytes are already in memory; longer ones
+// are read on from the request up to this limit.
+const maxMatchBodyBytes = 10 << 20
+
+// requestContent decodes the captured request body at most once per request,
+// and only when a candidate route has body matchers or the response reads
+// {{$request.body}} or {{$request.json.*}}.
+type requestContent struct {
+	body        loggedBody
+	contentType string
+	request     *http.Request
+	logger      *slog.Logger
+
+	fullRead bool
+	full     string
+	fullOK   bool
+
+	jsonDecoded bool
+	json        any
+	jsonOK      bool
+
+	formDecoded bool
+	form        url.Values
+	files       map[string][]formFile
+}
+
+// formFile describes an uploaded multipart part; its content is not kept.
+type formFile struct {
+	filename    string
+	contentType string
+}
+
+// text returns the whole request body. The logged body stops at
+// maxLoggedBodyBytes, so a longer body is read on from the request, up to
+// maxMatchBodyBytes, and the request body is rewound for the response. A body
+// over that limit is reported as not ok, and logged when c.logger is set.
+func (c *requestContent) text() (string, bool) {
+	if c.fullRead {
+		return c.full, c.fullOK
+	}
+	c.fullRead = true
+	if !c.body.truncated {
+		c.full, c.fullOK = c.body.text, true
+		return c.full, c.fullOK
+	}
+	if c.request == nil || c.request.Body == nil {
+		return "", false
+	}
+	body, err := io.ReadAll(io.LimitReader(c.request.Body, maxMatchBodyBytes+1))
+	c.request.Body = replayBody{
+		Reader: io.MultiReader(bytes.NewReader(body), c.request.Body),
+		Closer: c.request.Body,
+	}
+	if err != nil {
+		return "", false
+	}
+	if len(body) > maxMatchBodyBytes {
+		if c.logger != nil {
+			c.logger.Warn("request body too large to read for matchers or placeholders", "path", c.request.URL.Path, "limit", maxMatchBodyBytes)
+		}
+		return "", false
+	}
+	c.full, c.fullOK = string(body), true
+	return c.full, c.fullOK
+}
+
+func (c *requestContent) jsonValue() (any, bool) {
+	if !c.jsonDecoded {
+		c.jsonDecoded = true
+		if body, ok := c.text(); ok {
+			decoder := json.NewDecoder(strings.NewReader(body))
+			decoder.UseNumber()
+			c.jsonOK = decoder.Decode(&c.json) == nil
+		}
+	}
+	return c.json, c.jsonOK
+}
+
+func (c *requestContent) formValues() (url.Values, map[string][]formFile) {
+	if c.formDecoded {
+		return c.form, c.files
+	}
+	c.formDecoded = true
+	body, ok := c.text()
+	if !ok {
+		return nil, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(c.contentType)
+	if err != nil {
+		return nil, nil
+	}
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		c.form, _ = url.ParseQuery(body)
+	case "multipart/form-data":
+		c.form, c.files = parseMultipart(body, params["boundary"])
+	}
+	return c.form, c.files
+}
+
+// parseMultipart reads field values and file metadata from a multipart body.
+// A malformed part stops parsing but keeps the fields read so far.
+func parseMultipart(body, boundary string) (url.Values, map[string][]formFile) {
+	if boundary == "" {
+		return nil, nil
+	}
+	values := make(url.Values)
+	files := make(map[string][]formFile)
+	reader := multipart.NewReader(strings.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return values, files
+		}
+		name := part.FormName()
+		if filename := part.FileName(); filename != "" {
+			files[name] = append(files[name], formFile{
+				filename:    filename,
+				contentType: part.Header.Get("Content-Type"),
+			})
+			continue
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return values, files
+		}
+		values.Add(name, string(value))
+	}
+}
+
+// formMatches requires every expected field in a urlencoded or multipart body.
+// For uploaded files, "field" and "field.filename" match the part's filename
+// and "field.contentType" matches its Content-Type. Expected value "*"
+// matches any non-empty value.
+func formMatches(expected map[string]string, content *requestContent) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	values, files := content.formValues()
+	for field, expectedValue := range expected {
+		actual := formField(values, files, field)
+		if len(actual) == 0 {
+			return false
+		}
+		if expectedValue == "*" {
+			if strings.TrimSpace(actual[0]) == "" {
+				return false
+			}
+			continue
+		}
+		if !slices.Contains(actual, expectedValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func formField(values url.Values, files map[string][]formFile, field string) []string {
+	if actual, ok := values[field]; ok {
+		return actual
+	}
+	var actual []string
+	if uploads, ok := files[field]; ok {
+		for _, upload := range uploads {
+			actual = append(actual, upload.filename)
+		}
+		return actual
+	}
+	if name, ok := strings.CutSuffix(field, ".filename"); ok {
+		for _, upload := range files[name] {
+			actual = append(actual, upload.filename)
+		}
+	} else if name, ok := strings.CutSuffix(field, ".contentType"); ok {
+		for _, upload := range files[name] {
+			actual = append(actual, upload.contentType)
+		}
+	}
+	return actual
+}
+
+// jsonMatches requires every expected path to resolve in a JSON request body.
+// Expected value "*" matches any value at that path, including null.
+func jsonMatches(expected map[string]string, content *requestContent) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	document, ok := content.jsonValue()
+	if !ok {
+		return false
+	}
+	for path, expectedValue := range expected {
+		value, ok := lookupJSONPath(document, path)
+		if !ok {
+			return false
+		}
+		if expectedValue != "*" && !jsonValueEquals(value, expectedValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupJSONPath(document any, path string) (any, bool) {
+	segments, err := restclient.JSONPathSegments(path)
+	if err != nil {
+		return nil, false
+	}
+	value := document
+	for _, segment := range segments {
+		switch node := value.(type) {
+		case map[string]any:
+			child, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// jsonValueEquals compares a decoded JSON value with the text of a matcher.
+// Strings compare as-is, numbers compare numerically, and objects or arrays
+// compare against their compact JSON encoding.
+func jsonValueEquals(value any, expected string) bool {
+	switch v := value.(type) {
+	case string:
+		return v == expected
+	case json.Number:
+		if v.String() == expected {
 			return true
 		}
+		actual, err := v.Float64()
+		if err != nil {
+			return false
+		}
+		want, err := strconv.ParseFloat(expected, 64)
+		return err == nil && actual == want
+	case bool:
+		return strconv.FormatBool(v) == expected
+	case nil:
+		return expected == "null"
+	default:
+		decoder := json.NewDecoder(strings.NewReader(expected))
+		decoder.UseNumber()
+		var want any
+		if err := decoder.Decode(&want); err != nil {
+			return false
+		}
+		// Marshal sorts object keys, so key order in the matcher does not matter.
+		actualJSON, err := json.Marshal(v)
+		if err != nil {
+			return false
+		}
+		wantJSON, err := json.Marshal(want)
+		return err == nil && bytes.Equal(actualJSON, wantJSON)
 	}
-	return false
 }
@@ -67,7 +67,7 @@ func formatFixture(methods []restclient.Method) (string, error) {
 		}
 
 		filePath, hasFile := resolveFilePath(&method)
-		body, err := renderBody(method, nil, filePath, hasFile)
+		body, err := renderBody(method, nil, filePath, hasFile, newGenerator())
 		if err != nil {
 			return "", err
 		}
@@ -79,7 +79,7 @@ func formatFixture(methods []restclient.Method) (string, error) {
 			method.Path,
 			delay,
 			statusFromVariables(nil, method.Variables),
-			headerSize(responseHeaders(method, nil, filePath)),
+			headerSize(responseHeaders(method, nil, filePath, newGenerator())),
 			len(body),
 		)
 	}
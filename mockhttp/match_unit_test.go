@@ -1,7 +1,11 @@
 package mockhttp
 
 import (
+	"bytes"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
+	"net/textproto"
 	"net/url"
 	"reflect"
 	"testing"
@@ -48,6 +52,66 @@ func TestMatchPath(t *testing.T) {
 			requestPath: "/users/42",
 			wantOK:      false,
 		},
+		{
+			name:        "regex parameter",
+			pattern:     `/users/:id(\d+)`,
+			requestPath: "/users/42",
+			wantValues:  map[string]string{"id": "42"},
+			wantOK:      true,
+		},
+		{
+			name:        "regex parameter rejects value",
+			pattern:     `/users/:id(\d+)`,
+			requestPath: "/users/me",
+			wantOK:      false,
+		},
+		{
+			name:        "regex parameter matches unescaped value",
+			pattern:     `/files/:name(.*\.pdf)`,
+			requestPath: "/files/annual%20report.pdf",
+			wantValues:  map[string]string{"name": "annual report.pdf"},
+			wantOK:      true,
+		},
+		{
+			name:        "single segment wildcard",
+			pattern:     "/v1/*/status",
+			requestPath: "/v1/orders/status",
+			wantValues:  map[string]string{},
+			wantOK:      true,
+		},
+		{
+			name:        "single segment wildcard needs a segment",
+			pattern:     "/v1/*/status",
+			requestPath: "/v1/status",
+			wantOK:      false,
+		},
+		{
+			name:        "catch-all captures remainder",
+			pattern:     "/assets/**",
+			requestPath: "/assets/css/site%20main.css",
+			wantValues:  map[string]string{"rest": "css/site main.css"},
+			wantOK:      true,
+		},
+		{
+			name:        "catch-all matches no remaining segments",
+			pattern:     "/assets/**",
+			requestPath: "/assets",
+			wantValues:  map[string]string{"rest": ""},
+			wantOK:      true,
+		},
+		{
+			name:        "named catch-all",
+			pattern:     "/api/:version/:path*",
+			requestPath: "/api/v2/users/42/profile",
+			wantValues:  map[string]string{"version": "v2", "path": "users/42/profile"},
+			wantOK:      true,
+		},
+		{
+			name:        "catch-all still requires prefix",
+			pattern:     "/assets/**",
+			requestPath: "/static/app.js",
+			wantOK:      false,
+		},
 		{
 			name:        "different segment count",
 			pattern:     "/users/:id",
@@ -106,6 +170,30 @@ func TestHeaderMatches(t *testing.T) {
 			actual:   http.Header{"X-Trace": []string{""}},
 			want:     false,
 		},
+		{
+			name:     "regex matches any value",
+			expected: http.Header{"Accept": []string{"~^application/(json|xml)$"}},
+			actual:   http.Header{"Accept": []string{"text/html", "application/xml"}},
+			want:     true,
+		},
+		{
+			name:     "regex rejects other values",
+			expected: http.Header{"Accept": []string{"~^application/(json|xml)$"}},
+			actual:   http.Header{"Accept": []string{"text/html"}},
+			want:     false,
+		},
+		{
+			name:     "negation accepts missing header",
+			expected: http.Header{"Authorization": []string{"!"}},
+			actual:   http.Header{},
+			want:     true,
+		},
+		{
+			name:     "negation rejects present header",
+			expected: http.Header{"Authorization": []string{"!"}},
+			actual:   http.Header{"Authorization": []string{"Bearer x"}},
+			want:     false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -163,3 +251,179 @@ func TestQueryMatches(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONMatches(t *testing.T) {
+	body := `{"customer":{"tier":"gold","vip":true},"items":[{"sku":"A1","qty":2}],"total":10.50,"note":null}`
+	tests := []struct {
+		name     string
+		expected map[string]string
+		body     string
+		want     bool
+	}{
+		{name: "no matchers", expected: nil, body: "not json", want: true},
+		{name: "nested string", expected: map[string]string{"customer.tier": "gold"}, body: body, want: true},
+		{name: "array index", expected: map[string]string{"items[0].sku": "A1"}, body: body, want: true},
+		{name: "dotted array index", expected: map[string]string{"items.0.qty": "2"}, body: body, want: true},
+		{name: "number compares numerically", expected: map[string]string{"total": "10.5"}, body: body, want: true},
+		{name: "bool", expected: map[string]string{"customer.vip": "true"}, body: body, want: true},
+		{name: "null", expected: map[string]string{"note": "null"}, body: body, want: true},
+		{name: "object", expected: map[string]string{"customer": `{"vip":true,"tier":"gold"}`}, body: body, want: true},
+		{name: "wildcard present", expected: map[string]string{"note": "*"}, body: body, want: true},
+		{name: "wildcard missing", expected: map[string]string{"coupon": "*"}, body: body, want: false},
+		{name: "value mismatch", expected: map[string]string{"customer.tier": "silver"}, body: body, want: false},
+		{name: "index out of range", expected: map[string]string{"items[3].sku": "A1"}, body: body, want: false},
+		{name: "invalid json body", expected: map[string]string{"customer.tier": "gold"}, body: "tier=gold", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &requestContent{body: loggedBody{text: tt.body}}
+			if got := jsonMatches(tt.expected, content); got != tt.want {
+				t.Fatalf("jsonMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormMatches(t *testing.T) {
+	var multipartBody bytes.Buffer
+	writer := multipart.NewWriter(&multipartBody)
+	_ = writer.WriteField("username", "alice")
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="avatar"; filename="me.png"`)
+	header.Set("Content-Type", "image/png")
+	part, _ := writer.CreatePart(header)
+	_, _ = part.Write([]byte("\x89PNG"))
+	_ = writer.Close()
+
+	tests := []struct {
+		name        string
+		expected    map[string]string
+		contentType string
+		body        string
+		want        bool
+	}{
+		{
+			name:        "urlencoded field",
+			expected:    map[string]string{"username": "alice", "remember": "*"},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "username=alice&remember=on",
+			want:        true,
+		},
+		{
+			name:        "urlencoded mismatch",
+			expected:    map[string]string{"username": "bob"},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "username=alice",
+			want:        false,
+		},
+		{
+			name:        "multipart field and file",
+			expected:    map[string]string{"username": "alice", "avatar": "me.png", "avatar.contentType": "image/png"},
+			contentType: writer.FormDataContentType(),
+			body:        multipartBody.String(),
+			want:        true,
+		},
+		{
+			name:        "multipart filename mismatch",
+			expected:    map[string]string{"avatar.filename": "other.png"},
+			contentType: writer.FormDataContentType(),
+			body:        multipartBody.String(),
+			want:        false,
+		},
+		{
+			name:        "non-form body",
+			expected:    map[string]string{"username": "alice"},
+			contentType: "application/json",
+			body:        `{"username":"alice"}`,
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &requestContent{body: loggedBody{text: tt.body}, contentType: tt.contentType}
+			if got := formMatches(tt.expected, content); got != tt.want {
+				t.Fatalf("formMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryMatchesAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected map[string]string
+		query    string
+		want     bool
+	}{
+		{name: "presence", expected: map[string]string{"page": "*"}, query: "page=2", want: true},
+		{name: "presence allows empty value", expected: map[string]string{"debug": "*"}, query: "debug", want: true},
+		{name: "presence rejects missing", expected: map[string]string{"page": "*"}, query: "sort=asc", want: false},
+		{name: "regex", expected: map[string]string{"sort": "~^(asc|desc)$"}, query: "sort=desc", want: true},
+		{name: "regex mismatch", expected: map[string]string{"sort": "~^(asc|desc)$"}, query: "sort=name", want: false},
+		{name: "regex requires parameter", expected: map[string]string{"sort": "~.*"}, query: "", want: false},
+		{name: "absent", expected: map[string]string{"debug": "!"}, query: "page=1", want: true},
+		{name: "absent rejects present", expected: map[string]string{"debug": "!"}, query: "debug=", want: false},
+		{name: "exact any value", expected: map[string]string{"tag": "b"}, query: "tag=a&tag=b", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := queryMatchesAll(tt.expected, query); got != tt.want {
+				t.Fatalf("queryMatchesAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCookieMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected map[string]string
+		cookie   string
+		want     bool
+	}{
+		{name: "presence", expected: map[string]string{"session": "*"}, cookie: "theme=dark; session=abc", want: true},
+		{name: "presence rejects empty", expected: map[string]string{"session": "*"}, cookie: "session=", want: false},
+		{name: "presence rejects missing", expected: map[string]string{"session": "*"}, cookie: "theme=dark", want: false},
+		{name: "exact ignores order", expected: map[string]string{"theme": "dark"}, cookie: "session=abc; theme=dark", want: true},
+		{name: "exact mismatch", expected: map[string]string{"theme": "dark"}, cookie: "theme=light", want: false},
+		{name: "regex", expected: map[string]string{"session": "~^s-[0-9]+$"}, cookie: "session=s-42", want: true},
+		{name: "absent", expected: map[string]string{"session": "!"}, cookie: "theme=dark", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set("Cookie", tt.cookie)
+			if got := cookieMatches(tt.expected, cookieValues(request)); got != tt.want {
+				t.Fatalf("cookieMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{pattern: "", host: "anything.test:8080", want: true},
+		{pattern: "api.example.test", host: "api.example.test", want: true},
+		{pattern: "api.example.test", host: "API.example.test:8080", want: true},
+		{pattern: "api.example.test", host: "auth.example.test", want: false},
+		{pattern: "*.example.test", host: "cdn.example.test:443", want: true},
+		{pattern: "*.example.test", host: "a.b.example.test", want: true},
+		{pattern: "*.example.test", host: "example.test", want: false},
+		{pattern: "*.example.test", host: "badexample.test", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.host, func(t *testing.T) {
+			if got := hostMatches(tt.pattern, tt.host); got != tt.want {
+				t.Fatalf("hostMatches(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+			}
+		})
+	}
+}
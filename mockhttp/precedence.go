@@ -0,0 +1,173 @@
+package mockhttp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+// Precedence selects how the server chooses between several matching routes.
+type Precedence string
+
+const (
+	// PrecedenceSpecific serves the most specific matching routes and only
+	// rotates among routes that are equally specific.
+	PrecedenceSpecific Precedence = "specific"
+	// PrecedenceRotate rotates through every matching route in load order.
+	PrecedenceRotate Precedence = "rotate"
+)
+
+// ParsePrecedence validates a -precedence flag value.
+func ParsePrecedence(raw string) (Precedence, error) {
+	switch precedence := Precedence(raw); precedence {
+	case PrecedenceSpecific, PrecedenceRotate:
+		return precedence, nil
+	default:
+		return "", fmt.Errorf("unknown precedence %q (use %q or %q)", raw, PrecedenceSpecific, PrecedenceRotate)
+	}
+}
+
+// Path segment ranks, highest first. segmentMissing ranks a route that has
+// no segment where another route continues with a catch-all, so "/assets"
+// beats "/assets/**" for a request to "/assets".
+const (
+	segmentCatchAll = iota
+	segmentMissing
+	segmentWildcard
+	segmentParam
+	segmentPattern
+	segmentLiteral
+)
+
+// specificity ranks how narrowly a route matches. An exact host beats a
+// wildcard host, which beats no host. Path segments then compare left to
+// right; request matchers (query, header, cookie, body, form, and a
+// scenario's required state) break ties, then a concrete method beats ANY.
+type specificity struct {
+	host      int
+	segments  []int
+	matchers  int
+	anyMethod bool
+}
+
+func routeSpecificity(method *restclient.Method) specificity {
+	var spec specificity
+	segments, _ := pathSegments(method.Path)
+	for _, segment := range segments {
+		spec.segments = append(spec.segments, segmentRank(segment))
+	}
+	for _, values := range method.Query {
+		spec.matchers += len(values)
+	}
+	for _, values := range method.MatchHeaders {
+		spec.matchers += len(values)
+	}
+	spec.matchers += len(method.MatchQuery) + len(method.MatchCookies) + len(method.MatchJSON) + len(method.MatchForm)
+	if _, ok := method.Variables["requiredState"]; ok && method.Variables["scenario"] != "" {
+		spec.matchers++
+	}
+	spec.anyMethod = method.Method == restclient.MethodAny || isResource(method)
+	switch {
+	case method.Host == "":
+	case strings.HasPrefix(method.Host, "*."):
+		spec.host = 1
+	default:
+		spec.host = 2
+	}
+	return spec
+}
+
+func segmentRank(segment restclient.PathSegment) int {
+	switch segment.Kind {
+	case restclient.SegmentLiteral:
+		return segmentLiteral
+	case restclient.SegmentParam:
+		if segment.Pattern != nil {
+			return segmentPattern
+		}
+		return segmentParam
+	case restclient.SegmentWildcard:
+		return segmentWildcard
+	default:
+		return segmentCatchAll
+	}
+}
+
+// compareSpecificity returns a positive number when a is more specific than b,
+// a negative number when it is less specific, and zero when they tie.
+func compareSpecificity(a, b specificity) int {
+	if diff := a.host - b.host; diff != 0 {
+		return diff
+	}
+	for i := range max(len(a.segments), len(b.segments)) {
+		if diff := rankAt(a.segments, i) - rankAt(b.segments, i); diff != 0 {
+			return diff
+		}
+	}
+	if diff := a.matchers - b.matchers; diff != 0 {
+		return diff
+	}
+	return methodRank(a) - methodRank(b)
+}
+
+func methodRank(spec specificity) int {
+	if spec.anyMethod {
+		return 0
+	}
+	return 1
+}
+
+func rankAt(ranks []int, i int) int {
+	if i < len(ranks) {
+		return ranks[i]
+	}
+	return segmentMissing
+}
+
+// mostSpecific keeps only the candidates that tie for the highest specificity,
+// preserving load order among them.
+func mostSpecific(candidates []routeMatch) []routeMatch {
+	if len(candidates) < 2 {
+		return candidates
+	}
+	specs := make([]specificity, len(candidates))
+	best := 0
+	for i, candidate := range candidates {
+		specs[i] = routeSpecificity(candidate.method)
+		if compareSpecificity(specs[i], specs[best]) > 0 {
+			best = i
+		}
+	}
+	var winners []routeMatch
+	for i, candidate := range candidates {
+		if compareSpecificity(specs[i], specs[best]) == 0 {
+			winners = append(winners, candidate)
+		}
+	}
+	return winners
+}
+
+// precedenceOrder returns route indexes sorted from most to least specific
+// (stable for ties) and the 1-based rank of each index; tied routes share a rank.
+func precedenceOrder(methods []restclient.Method) ([]int, []int) {
+	specs := make([]specificity, len(methods))
+	order := make([]int, len(methods))
+	for i := range methods {
+		specs[i] = routeSpecificity(&methods[i])
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return compareSpecificity(specs[b], specs[a])
+	})
+	ranks := make([]int, len(methods))
+	rank := 0
+	for i, index := range order {
+		if i == 0 || compareSpecificity(specs[index], specs[order[i-1]]) != 0 {
+			rank++
+		}
+		ranks[index] = rank
+	}
+	return order, ranks
+}
@@ -0,0 +1,77 @@
+package mockhttp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+func TestCompareSpecificity(t *testing.T) {
+	route := func(path string) *restclient.Method {
+		return &restclient.Method{Path: path}
+	}
+	tests := []struct {
+		name string
+		more *restclient.Method
+		less *restclient.Method
+	}{
+		{name: "literal beats param", more: route("/users/me"), less: route("/users/:id")},
+		{name: "regex param beats param", more: route(`/users/:id(\d+)`), less: route("/users/:id")},
+		{name: "param beats wildcard", more: route("/users/:id"), less: route("/users/*")},
+		{name: "wildcard beats catch-all", more: route("/users/*"), less: route("/users/**")},
+		{name: "earlier segment decides", more: route("/users/:id/posts"), less: route("/:kind/me/posts")},
+		{name: "exact path beats empty catch-all", more: route("/assets"), less: route("/assets/**")},
+		{
+			name: "more matchers win",
+			more: &restclient.Method{Path: "/users", Query: url.Values{"page": {"1"}}, MatchHeaders: http.Header{"X-Tenant": {"a"}}},
+			less: &restclient.Method{Path: "/users", Query: url.Values{"page": {"1"}}},
+		},
+		{
+			name: "exact host beats wildcard host",
+			more: &restclient.Method{Host: "api.example.test", Path: "/:kind"},
+			less: &restclient.Method{Host: "*.example.test", Path: "/users"},
+		},
+		{
+			name: "wildcard host beats no host",
+			more: &restclient.Method{Host: "*.example.test", Path: "/:kind"},
+			less: &restclient.Method{Path: "/users"},
+		},
+		{
+			name: "concrete method beats ANY",
+			more: &restclient.Method{Method: http.MethodGet, Path: "/users"},
+			less: &restclient.Method{Method: restclient.MethodAny, Path: "/users"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			more, less := routeSpecificity(tt.more), routeSpecificity(tt.less)
+			if got := compareSpecificity(more, less); got <= 0 {
+				t.Fatalf("compareSpecificity(more, less) = %d, want > 0", got)
+			}
+			if got := compareSpecificity(less, more); got >= 0 {
+				t.Fatalf("compareSpecificity(less, more) = %d, want < 0", got)
+			}
+		})
+	}
+}
+
+func TestRoutesFromMethodsOrdersByPrecedence(t *testing.T) {
+	methods := []restclient.Method{
+		{Name: "any", Method: http.MethodGet, Path: "/users/*"},
+		{Name: "by id", Method: http.MethodGet, Path: "/users/:id"},
+		{Name: "me", Method: http.MethodGet, Path: "/users/me"},
+		{Name: "me again", Method: http.MethodGet, Path: "/users/me"},
+	}
+	routes := RoutesFromMethods(methods)
+	want := []struct {
+		name string
+		rank int
+	}{{"me", 1}, {"me again", 1}, {"by id", 2}, {"any", 3}}
+	for i, w := range want {
+		if routes[i].Name != w.name || routes[i].Precedence != w.rank {
+			t.Fatalf("routes[%d] = %s rank %d, want %s rank %d", i, routes[i].Name, routes[i].Precedence, w.name, w.rank)
+		}
+	}
+}
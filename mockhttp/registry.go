@@ -0,0 +1,66 @@
+package mockhttp
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// GeneratorFunc produces the value of a registered {{$name}} or
+// {{$name(args)}} placeholder. args holds the trimmed placeholder arguments
+// (nil without parentheses) and r is the request being answered; r is nil
+// when a value is generated outside a request.
+type GeneratorFunc func(args []string, r *http.Request) string
+
+var (
+	customGeneratorsMu sync.RWMutex
+	customGenerators   = make(map[string]GeneratorFunc)
+)
+
+// generatorNamePattern matches names usable as {{$name}} placeholders.
+var generatorNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RegisterGenerator makes fn available as the {{$name}} placeholder, with
+// arguments as {{$name(a,b)}}, in every Server, including $template=go
+// sections where it is the function name. Register generators before loading
+// routes so templates and load-time warnings see them. Registering a name
+// again replaces its generator. It panics if name is not a valid placeholder
+// name, is a built-in generator or $template=go function, or fn is nil.
+func RegisterGenerator(name string, fn GeneratorFunc) {
+	if !generatorNamePattern.MatchString(name) {
+		panic(fmt.Sprintf("mockhttp: invalid generator name %q", name))
+	}
+	if slices.Contains(generatorNames, name) {
+		panic(fmt.Sprintf("mockhttp: generator %q is built in", name))
+	}
+	if isTemplateFunc(name) {
+		panic(fmt.Sprintf("mockhttp: generator %q would shadow the $template=go function of that name", name))
+	}
+	if fn == nil {
+		panic(fmt.Sprintf("mockhttp: nil generator for %q", name))
+	}
+	customGeneratorsMu.Lock()
+	defer customGeneratorsMu.Unlock()
+	customGenerators[name] = fn
+}
+
+func customGenerator(name string) (GeneratorFunc, bool) {
+	customGeneratorsMu.RLock()
+	defer customGeneratorsMu.RUnlock()
+	fn, ok := customGenerators[name]
+	return fn, ok
+}
+
+// customGeneratorNames returns the registered generator names.
+func customGeneratorNames() []string {
+	customGeneratorsMu.RLock()
+	defer customGeneratorsMu.RUnlock()
+	names := make([]string, 0, len(customGenerators))
+	for name := range customGenerators {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
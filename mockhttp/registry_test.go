@@ -0,0 +1,120 @@
+package mockhttp
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+// registerTestGenerator registers fn for the duration of the test.
+func registerTestGenerator(t *testing.T, name string, fn GeneratorFunc) {
+	t.Helper()
+	RegisterGenerator(name, fn)
+	t.Cleanup(func() {
+		customGeneratorsMu.Lock()
+		defer customGeneratorsMu.Unlock()
+		delete(customGenerators, name)
+	})
+}
+
+func TestRegisteredGeneratorsRenderInPlaceholdersAndTemplates(t *testing.T) {
+	registerTestGenerator(t, "sku", func(args []string, r *http.Request) string {
+		prefix := "SKU"
+		if len(args) > 0 {
+			prefix = args[0]
+		}
+		return prefix + "-" + r.URL.Query().Get("n")
+	})
+	registerTestGenerator(t, "tenantId", func(args []string, r *http.Request) string {
+		return r.Header.Get("X-Tenant")
+	})
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Product
+GET /product
+X-Tenant-Id: {{$tenantId}}
+
+{{$sku}} {{$sku(BOOK)}} {{$sku:item}} {{$sku | lower}}
+
+### Template product
+# $template=go
+GET /template
+
+{{sku}} {{sku "PEN"}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var logs bytes.Buffer
+	server := New(methods, slog.New(slog.NewTextHandler(&logs, nil)))
+	if strings.Contains(logs.String(), "level=WARN") {
+		t.Fatalf("logs = %s, want no warnings for registered generators", logs.String())
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/product?n=7", nil)
+	request.Header.Set("X-Tenant", "acme")
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	if got := response.Body.String(); got != "SKU-7 BOOK-7 SKU-7 sku-7" {
+		t.Fatalf("body = %q", got)
+	}
+	if got := response.Header().Get("X-Tenant-Id"); got != "acme" {
+		t.Fatalf("X-Tenant-Id = %q, want acme", got)
+	}
+	if got := serveBody(server, http.MethodGet, "/template?n=2"); got != "SKU-2 PEN-2" {
+		t.Fatalf("template body = %q", got)
+	}
+}
+
+func TestRegisterGeneratorRejectsInvalidNames(t *testing.T) {
+	fn := func([]string, *http.Request) string { return "" }
+	for name, register := range map[string]func(){
+		"empty":            func() { RegisterGenerator("", fn) },
+		"dotted":           func() { RegisterGenerator("request.sku", fn) },
+		"built in":         func() { RegisterGenerator("uuid", fn) },
+		"helper":           func() { RegisterGenerator("json", fn) },
+		"filter":           func() { RegisterGenerator("truncate", fn) },
+		"template builtin": func() { RegisterGenerator("len", fn) },
+		"nil func":         func() { RegisterGenerator("sku", nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("RegisterGenerator() did not panic")
+				}
+			}()
+			register()
+		})
+	}
+}
+
+func TestWarnMethodConfigKnowsRegisteredGenerators(t *testing.T) {
+	registerTestGenerator(t, "iban", func([]string, *http.Request) string { return "DE00" })
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Account
+# $iban=fixed
+GET /account
+
+{{$iban}} {{$iban(DE)}} {{$ibam(DE)}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var logs bytes.Buffer
+	warnMethodConfig(slog.New(slog.NewTextHandler(&logs, nil)), methods)
+	logText := logs.String()
+	if !strings.Contains(logText, "overrides registered generator {{$iban}}") {
+		t.Fatalf("logs = %s, want override warning for $iban", logText)
+	}
+	if strings.Count(logText, "invalid placeholder") != 1 || !strings.Contains(logText, "ibam") {
+		t.Fatalf("logs = %s, want one invalid placeholder warning for ibam", logText)
+	}
+
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if got := serveBody(server, http.MethodGet, "/account"); got != "fixed DE00 " {
+		t.Fatalf("body = %q, want the variable, the generator and an empty unknown", got)
+	}
+}
@@ -1,9 +1,9 @@
 package mockhttp
 
 import (
+	"cmp"
 	"fmt"
 	"log/slog"
-	"math/rand/v2"
 	"mime"
 	"net/http"
 	"os"
@@ -11,21 +11,15 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/sspencer/mock/restclient"
-
-	"github.com/jaswdr/faker"
 )
 
-var placeholderPattern = regexp.MustCompile(`\{\{\$([A-Za-z_][A-Za-z0-9_]*)}}`)
-
-var fakerPool = sync.Pool{
-	New: func() any {
-		return faker.New()
-	},
-}
+// placeholderPattern matches {{$name}}, {{$name(arg,...)}}, named instances
+// such as {{$uuid:order}}, and any of them followed by filters such as
+// {{$sentence | truncate(20) | json}}. The groups hold the name, the
+// parenthesized arguments, the instance name and the raw filter chain.
+var placeholderPattern = regexp.MustCompile(`\{\{\$([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z0-9_-]+)*)(\([^(){}]*\))?(?::([A-Za-z0-9_-]+))?((?:\s*\|\s*[A-Za-z][A-Za-z0-9]*(?:\([^(){}]*\))?)*)\s*}}`)
 
 func statusFromVariables(logger *slog.Logger, variables map[string]string) int {
 	raw, ok := variables["status"]
@@ -58,24 +52,30 @@ func statusAllowsBody(status int) bool {
 	return status != http.StatusNoContent && status != http.StatusNotModified && (status < 100 || status >= 200)
 }
 
-func responseHeaders(method restclient.Method, values map[string]string, filePath string) http.Header {
+func responseHeaders(method restclient.Method, values map[string]string, filePath string, gen *generator) http.Header {
 	headers := method.Headers.Clone()
 	for name, headerValues := range headers {
 		for i, value := range headerValues {
-			headerValues[i] = expandPlaceholders(value, method, values)
+			headerValues[i] = expandPlaceholders(value, method, values, gen)
 		}
 		headers[name] = headerValues
 	}
+	setFileContentType(headers, method, filePath)
+	return headers
+}
+
+// setFileContentType infers Content-Type from a $file extension when the
+// section sets none and has no inline body.
+func setFileContentType(headers http.Header, method restclient.Method, filePath string) {
 	if headers.Get("Content-Type") != "" || method.Body != "" || filePath == "" {
-		return headers
+		return
 	}
 	if contentType := mime.TypeByExtension(filepath.Ext(filePath)); contentType != "" {
 		headers.Set("Content-Type", contentType)
 	}
-	return headers
 }
 
-func renderBody(method restclient.Method, values map[string]string, filePath string, hasFile bool) ([]byte, error) {
+func renderBody(method restclient.Method, values map[string]string, filePath string, hasFile bool, gen *generator) ([]byte, error) {
 	if method.Body == "" {
 		if hasFile {
 			body, err := os.ReadFile(filePath)
@@ -84,31 +84,78 @@ func renderBody(method restclient.Method, values map[string]string, filePath str
 			}
 			// Expand placeholders only when the file looks like text.
 			if isMostlyText(body) {
-				return []byte(expandPlaceholders(string(body), method, values)), nil
+				return []byte(expandBody(string(body), method, values, gen, filePath)), nil
 			}
 			return body, nil
 		}
 		return nil, nil
 	}
 
-	return []byte(expandPlaceholders(method.Body, method, values)), nil
+	return []byte(expandBody(method.Body, method, values, gen, filePath)), nil
+}
+
+func expandPlaceholders(input string, method restclient.Method, values map[string]string, gen *generator) string {
+	return expandText(input, method, values, gen, false)
 }
 
-func expandPlaceholders(input string, method restclient.Method, values map[string]string) string {
-	return placeholderPattern.ReplaceAllStringFunc(input, func(match string) string {
-		parts := placeholderPattern.FindStringSubmatch(match)
-		if len(parts) != 2 {
-			return match
+// expandText replaces placeholders in input. With jsonStrings set, values
+// that land inside a JSON string literal are escaped for it; values elsewhere,
+// such as numbers and echoed objects, are inserted as-is.
+func expandText(input string, method restclient.Method, values map[string]string, gen *generator, jsonStrings bool) string {
+	var out strings.Builder
+	var state jsonStringState
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(input, -1) {
+		literal := input[last:loc[0]]
+		out.WriteString(literal)
+		state.scan(literal)
+		last = loc[1]
+
+		parts := make([]string, len(loc)/2)
+		for i := range parts {
+			if loc[2*i] >= 0 {
+				parts[i] = input[loc[2*i]:loc[2*i+1]]
+			}
 		}
-		key := parts[1]
-		if value, ok := values[key]; ok {
-			return value
+		value := placeholderValue(parts, method, values, gen)
+		if jsonStrings && state.inString {
+			value = strings.TrimSuffix(strings.TrimPrefix(jsonString(value), `"`), `"`)
 		}
-		if value, ok := method.Variables[key]; ok {
-			return value
+		out.WriteString(value)
+	}
+	out.WriteString(input[last:])
+	return out.String()
+}
+
+// placeholderValue resolves one placeholderPattern submatch and applies its
+// filters. Invalid filters render an empty string.
+func placeholderValue(parts []string, method restclient.Method, values map[string]string, gen *generator) string {
+	key, args, instance := parsePlaceholder(parts)
+	var value string
+	switch {
+	case instance != "":
+		value = gen.instance(instance+values[repeatScopeKey], key, args...)
+	case args != nil:
+		value = gen.value(key, args...)
+	default:
+		if v, ok := requestValue(key, gen.request, gen.content); ok {
+			value = v
+		} else if v, ok := values[key]; ok {
+			value = v
+		} else if v, ok := method.Variables[key]; ok {
+			value = v
+		} else {
+			value = gen.value(key)
 		}
-		return generatedValue(key)
-	})
+	}
+	if parts[4] == "" {
+		return value
+	}
+	filtered, err := applyFilters(value, parts[4])
+	if err != nil {
+		return ""
+	}
+	return filtered
 }
 
 func isMostlyText(body []byte) bool {
@@ -145,55 +192,56 @@ func resolveFilePath(method *restclient.Method) (string, bool) {
 	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+sep) {
 		return "", false
 	}
-	return filepath.Join(filepath.Dir(method.Source), cleaned), true
+	return filepath.Join(filepath.Dir(cmp.Or(method.FileSource, method.Source)), cleaned), true
 }
 
-func generatedValue(key string) string {
-	f := fakerPool.Get().(faker.Faker)
-	defer fakerPool.Put(f)
-
-	switch key {
-	case "integer":
-		return fmt.Sprint(f.UInt16())
-	case "float":
-		return fmt.Sprint(f.Float32(2, 0, 100_000))
-	case "bool":
-		return fmt.Sprint(f.Boolean().Bool())
-	case "uuid":
-		return f.UUID().V4()
-	case "guid":
-		return f.UUID().V4()
-	case "timestamp":
-		return fmt.Sprint(f.Time().Unix(time.Now()))
-	case "isoTimestamp":
-		return f.Time().ISO8601(time.Now())
-	case "name":
-		return f.Person().Name()
-	case "firstName":
-		return f.Person().FirstName()
-	case "lastName":
-		return f.Person().LastName()
-	case "phone":
-		return f.Phone().Number()
-	case "user":
-		return f.Internet().User()
-	case "email":
-		return f.Internet().Email()
-	case "url":
-		return f.Internet().URL()
-	case "server":
-		return f.Internet().Domain()
-	case "hash":
-		return f.Hash().MD5()
-	case "file":
-		return f.File().AbsoluteFilePath(3 + rand.IntN(4))
-	case "sentence":
-		return f.Lorem().Sentence(8 + rand.IntN(9))
-	case "paragraph":
-		return f.Lorem().Paragraph(3 + rand.IntN(2))
-	case "article":
-		return f.Lorem().Paragraph(5 + rand.IntN(3))
-	default:
-		return ""
+// parsePlaceholder splits a placeholderPattern submatch into its key, its
+// arguments and its instance name. args is nil for a bare {{$name}} and empty
+// but non-nil for {{$name()}}.
+func parsePlaceholder(parts []string) (key string, args []string, instance string) {
+	key, instance = parts[1], parts[3]
+	raw, ok := strings.CutPrefix(parts[2], "(")
+	if !ok {
+		return key, nil, instance
+	}
+	raw = strings.TrimSuffix(raw, ")")
+	if strings.TrimSpace(raw) == "" {
+		return key, []string{}, instance
+	}
+	args = strings.Split(raw, ",")
+	for i, arg := range args {
+		args[i] = strings.TrimSpace(arg)
+	}
+	return key, args, instance
+}
+
+// placeholderErrors reports invalid {{$name(args)}} and {{$name:instance}}
+// generators and invalid filters in a section's body and response headers,
+// for load-time warnings.
+func placeholderErrors(method restclient.Method) []error {
+	var errs []error
+	check := func(text string) {
+		for _, parts := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			if _, err := applyFilters("", parts[4]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", parts[0], err))
+			}
+			key, args, instance := parsePlaceholder(parts)
+			if args == nil && instance == "" {
+				continue
+			}
+			if _, ok := customGenerator(key); ok {
+				continue
+			}
+			if _, err := newGenerator().generate(key, args); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", parts[0], err))
+			}
+		}
+	}
+	check(method.Body)
+	for _, values := range method.Headers {
+		for _, value := range values {
+			check(value)
+		}
 	}
+	return errs
 }
@@ -1,6 +1,11 @@
 package mockhttp
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
 
 func TestGeneratedValueSupportsDocumentedKeys(t *testing.T) {
 	keys := []string{
@@ -20,6 +25,7 @@ func TestGeneratedValueSupportsDocumentedKeys(t *testing.T) {
 		"guid",
 		"timestamp",
 		"isoTimestamp",
+		"date",
 		"file",
 		"sentence",
 		"paragraph",
@@ -28,15 +34,39 @@ func TestGeneratedValueSupportsDocumentedKeys(t *testing.T) {
 
 	for _, key := range keys {
 		t.Run(key, func(t *testing.T) {
-			if got := generatedValue(key); got == "" {
-				t.Fatalf("generatedValue(%q) = empty string, want value", key)
+			if got := newGenerator().value(key); got == "" {
+				t.Fatalf("value(%q) = empty string, want value", key)
 			}
 		})
 	}
 }
 
 func TestGeneratedValueReturnsEmptyForUnknownKey(t *testing.T) {
-	if got := generatedValue("missing"); got != "" {
-		t.Fatalf("generatedValue(missing) = %q, want empty string", got)
+	if got := newGenerator().value("missing"); got != "" {
+		t.Fatalf("value(missing) = %q, want empty string", got)
+	}
+}
+
+func TestExpandPlaceholdersWithArguments(t *testing.T) {
+	method := restclient.Method{Variables: map[string]string{"color": "teal"}}
+	got := expandPlaceholders("{{$integer(4, 4)}} {{$oneOf(a)}} {{$color}} {{$integer(x)}} {{$uuid()}}", method, nil, newGenerator())
+	fields := strings.Split(got, " ")
+	if len(fields) != 5 || fields[0] != "4" || fields[1] != "a" || fields[2] != "teal" || fields[3] != "" || len(fields[4]) != 36 {
+		t.Fatalf("expandPlaceholders() = %q", got)
+	}
+}
+
+func TestExpandPlaceholdersRepeatsNamedInstances(t *testing.T) {
+	gen := newGenerator()
+	got := expandPlaceholders("{{$uuid:order}} {{$uuid:order}} {{$uuid:item}} {{$uuid}} {{$integer(1,1000000):n}} {{$integer(1,1000000):n}}", restclient.Method{}, nil, gen)
+	fields := strings.Fields(got)
+	if len(fields) != 6 || fields[0] != fields[1] || fields[0] == fields[2] || fields[0] == fields[3] || fields[4] != fields[5] {
+		t.Fatalf("expandPlaceholders() = %q", got)
+	}
+	if again := expandPlaceholders("{{$uuid:order}}", restclient.Method{}, nil, gen); again != fields[0] {
+		t.Fatalf("same generator rendered order %q, want %q", again, fields[0])
+	}
+	if other := expandPlaceholders("{{$uuid:order}}", restclient.Method{}, nil, newGenerator()); other == fields[0] {
+		t.Fatalf("new generator repeated order %q", other)
 	}
 }
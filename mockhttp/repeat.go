@@ -0,0 +1,195 @@
+package mockhttp
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+// maxRepeat caps how many times a {{#repeat}} block renders.
+const maxRepeat = 1000
+
+// repeatScopeKey is the internal values key that tells named instances which
+// repetition they belong to, so {{$uuid:item}} is fresh in every item.
+const repeatScopeKey = "\x00repeat"
+
+// repeatTagPattern matches {{#repeat COUNT}} and {{/repeat}} tags.
+var repeatTagPattern = regexp.MustCompile(`\{\{(?:#repeat(?:\s+([^{}]*?))?\s*|/repeat)}}`)
+
+// repeatNode is literal text or a repeat block in a parsed body.
+type repeatNode struct {
+	text   string
+	repeat *repeatBlock
+}
+
+// repeatBlock is one {{#repeat}} block. Its count is a fixed number, a
+// min..max range, or a request value such as $limit with an optional fallback.
+type repeatBlock struct {
+	param    string
+	min, max int
+	body     []repeatNode
+}
+
+// parsedRepeats caches parsed bodies by their text.
+var parsedRepeats textCache[parsedRepeat]
+
+type parsedRepeat struct {
+	nodes []repeatNode
+	err   error
+}
+
+// parseRepeats splits text into literal text and (possibly nested) repeat
+// blocks.
+func parseRepeats(text string) ([]repeatNode, error) {
+	if parsed, ok := parsedRepeats.load(text); ok {
+		return parsed.nodes, parsed.err
+	}
+	nodes, err := parseRepeatNodes(text)
+	parsedRepeats.store(text, parsedRepeat{nodes: nodes, err: err})
+	return nodes, err
+}
+
+func parseRepeatNodes(text string) ([]repeatNode, error) {
+	root := &repeatBlock{}
+	open := []*repeatBlock{root}
+	start := 0
+	for _, tag := range repeatTagPattern.FindAllStringSubmatchIndex(text, -1) {
+		block := open[len(open)-1]
+		if tag[0] > start {
+			block.body = append(block.body, repeatNode{text: text[start:tag[0]]})
+		}
+		start = tag[1]
+		if strings.HasPrefix(text[tag[0]:], "{{/") {
+			if len(open) == 1 {
+				return nil, fmt.Errorf("{{/repeat}} without {{#repeat}}")
+			}
+			open = open[:len(open)-1]
+			continue
+		}
+		spec := ""
+		if tag[2] >= 0 {
+			spec = text[tag[2]:tag[3]]
+		}
+		inner, err := parseRepeatCount(spec)
+		if err != nil {
+			return nil, err
+		}
+		block.body = append(block.body, repeatNode{repeat: inner})
+		open = append(open, inner)
+	}
+	if len(open) > 1 {
+		return nil, fmt.Errorf("{{#repeat}} without {{/repeat}}")
+	}
+	if start < len(text) {
+		root.body = append(root.body, repeatNode{text: text[start:]})
+	}
+	return root.body, nil
+}
+
+// parseRepeatCount parses a {{#repeat}} count: "N", "N..M", "$name" or
+// "$name N" / "$name N..M", where N or N..M is used when the request has no
+// valid value for name.
+func parseRepeatCount(spec string) (*repeatBlock, error) {
+	fields := strings.Fields(spec)
+	block := &repeatBlock{}
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "$") {
+		block.param = strings.TrimPrefix(fields[0], "$")
+		if block.param == "" {
+			return nil, fmt.Errorf("{{#repeat %s}} needs a value name after $", spec)
+		}
+		fields = fields[1:]
+		if len(fields) == 0 {
+			return block, nil
+		}
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("{{#repeat %s}} needs a count such as 5, 5..20 or $limit", spec)
+	}
+	low, high, isRange := strings.Cut(fields[0], "..")
+	if !isRange {
+		high = low
+	}
+	var errLow, errHigh error
+	block.min, errLow = strconv.Atoi(low)
+	block.max, errHigh = strconv.Atoi(high)
+	if errLow != nil || errHigh != nil || block.min < 0 || block.min > block.max {
+		return nil, fmt.Errorf("{{#repeat %s}} count %q is not N or min..max", spec, fields[0])
+	}
+	return block, nil
+}
+
+// count returns how many times the block renders for this response.
+func (b *repeatBlock) count(values map[string]string, gen *generator) int {
+	if b.param != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(values[b.param])); err == nil && n >= 0 {
+			return min(n, maxRepeat)
+		}
+	}
+	n := b.min
+	if b.max > b.min {
+		n += gen.rand.IntN(b.max - b.min + 1)
+	}
+	return min(n, maxRepeat)
+}
+
+// expandBody expands repeat blocks and placeholders in a response body. Each
+// repetition sees {{$index}} (from 0) and {{$position}} (from 1) and draws
+// fresh generated values. Repetitions of a JSON response are joined with
+// commas, so a block can sit directly inside a JSON array. A body whose blocks
+// do not parse has only its placeholders expanded.
+func expandBody(text string, method restclient.Method, values map[string]string, gen *generator, filePath string) string {
+	jsonStrings := escapeJSONStrings(method, filePath)
+	nodes, err := parseRepeats(text)
+	if err != nil {
+		return expandText(text, method, values, gen, jsonStrings)
+	}
+	r := repeatRenderer{method: method, gen: gen, jsonStrings: jsonStrings, jsonList: isJSONResponse(method, filePath)}
+	var out strings.Builder
+	r.render(&out, nodes, values)
+	return out.String()
+}
+
+type repeatRenderer struct {
+	method      restclient.Method
+	gen         *generator
+	jsonStrings bool
+	jsonList    bool
+}
+
+func (r repeatRenderer) render(out *strings.Builder, nodes []repeatNode, values map[string]string) {
+	for _, node := range nodes {
+		if node.repeat == nil {
+			out.WriteString(expandText(node.text, r.method, values, r.gen, r.jsonStrings))
+			continue
+		}
+		n := node.repeat.count(values, r.gen)
+		for i := range n {
+			item := maps.Clone(values)
+			if item == nil {
+				item = make(map[string]string)
+			}
+			item["index"] = strconv.Itoa(i)
+			item["position"] = strconv.Itoa(i + 1)
+			item[repeatScopeKey] = values[repeatScopeKey] + "/" + strconv.Itoa(i)
+
+			var rendered strings.Builder
+			r.render(&rendered, node.repeat.body, item)
+			text := rendered.String()
+			if r.jsonList && i < n-1 {
+				text = strings.TrimRight(text, " \t\r\n") + ","
+			}
+			out.WriteString(text)
+		}
+	}
+}
+
+// repeatErrors reports malformed {{#repeat}} blocks in a section's inline
+// body, for load-time warnings.
+func repeatErrors(method restclient.Method) error {
+	_, err := parseRepeats(method.Body)
+	return err
+}
@@ -0,0 +1,149 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+func TestParseRepeatCount(t *testing.T) {
+	tests := []struct {
+		spec  string
+		want  repeatBlock
+		valid bool
+	}{
+		{"5", repeatBlock{min: 5, max: 5}, true},
+		{"5..20", repeatBlock{min: 5, max: 20}, true},
+		{"$limit", repeatBlock{param: "limit"}, true},
+		{"$limit 10", repeatBlock{param: "limit", min: 10, max: 10}, true},
+		{"$limit 1..3", repeatBlock{param: "limit", min: 1, max: 3}, true},
+		{"", repeatBlock{}, false},
+		{"20..5", repeatBlock{}, false},
+		{"-1", repeatBlock{}, false},
+		{"$", repeatBlock{}, false},
+		{"5 6", repeatBlock{}, false},
+		{"many", repeatBlock{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseRepeatCount(tt.spec)
+			if !tt.valid {
+				if err == nil {
+					t.Fatalf("parseRepeatCount(%q) error = nil, want error", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRepeatCount(%q) error = %v", tt.spec, err)
+			}
+			if got.param != tt.want.param || got.min != tt.want.min || got.max != tt.want.max {
+				t.Fatalf("parseRepeatCount(%q) = %+v, want %+v", tt.spec, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRepeatsRejectsUnbalancedBlocks(t *testing.T) {
+	for _, text := range []string{"{{#repeat 2}}x", "x{{/repeat}}", "{{#repeat 2}}{{#repeat 2}}{{/repeat}}"} {
+		if _, err := parseRepeats(text); err == nil {
+			t.Errorf("parseRepeats(%q) error = nil, want error", text)
+		}
+	}
+}
+
+func TestExpandBodyRepeats(t *testing.T) {
+	jsonMethod := restclient.Method{Headers: http.Header{"Content-Type": {"application/json"}}, Body: "x"}
+	tests := []struct {
+		name   string
+		method restclient.Method
+		text   string
+		values map[string]string
+		want   string
+	}{
+		{"json commas", jsonMethod, "[{{#repeat 3}}\n  {\"i\":{{$index}},\"n\":{{$position}}}\n{{/repeat}}]", nil, "[\n  {\"i\":0,\"n\":1},\n  {\"i\":1,\"n\":2},\n  {\"i\":2,\"n\":3}\n]"},
+		{"text", restclient.Method{Body: "x"}, "{{#repeat 3}}{{$position}};{{/repeat}}", nil, "1;2;3;"},
+		{"zero", jsonMethod, "[{{#repeat 0}}{}{{/repeat}}]", nil, "[]"},
+		{"param", jsonMethod, "[{{#repeat $limit 1}}{{$index}}{{/repeat}}]", map[string]string{"limit": "4"}, "[0,1,2,3]"},
+		{"param fallback", jsonMethod, "[{{#repeat $limit 2}}{{$index}}{{/repeat}}]", map[string]string{"limit": "lots"}, "[0,1]"},
+		{"nested", jsonMethod, "[{{#repeat 2}}[{{#repeat 2}}\"{{$index}}\"{{/repeat}}]{{/repeat}}]", nil, `[["0","1"],["0","1"]]`},
+		{"capped", restclient.Method{Body: "x"}, "{{#repeat $limit}}.{{/repeat}}", map[string]string{"limit": "5000"}, strings.Repeat(".", maxRepeat)},
+		{"unbalanced", restclient.Method{Body: "x"}, "{{#repeat 2}}{{$index}}", map[string]string{"index": "i"}, "{{#repeat 2}}i"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandBody(tt.text, tt.method, tt.values, newGenerator(), ""); got != tt.want {
+				t.Fatalf("expandBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandBodyRepeatRangeAndFreshValues(t *testing.T) {
+	jsonMethod := restclient.Method{Headers: http.Header{"Content-Type": {"application/json"}}, Body: "x"}
+	text := `[{{#repeat 5..20}}{"id":"{{$uuid}}","ref":"{{$uuid:item}}","again":"{{$uuid:item}}"}{{/repeat}}]`
+	var items []map[string]string
+	if err := json.Unmarshal([]byte(expandBody(text, jsonMethod, nil, newGenerator(), "")), &items); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if len(items) < 5 || len(items) > 20 {
+		t.Fatalf("len(items) = %d, want 5..20", len(items))
+	}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item["ref"] != item["again"] || seen[item["id"]] || seen[item["ref"]] {
+			t.Fatalf("items = %#v, want fresh ids and a repeated ref per item", items)
+		}
+		seen[item["id"]], seen[item["ref"]] = true, true
+	}
+}
+
+func TestServerRepeatsFileTemplateByQueryLimit(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte(`[{{#repeat $limit 3}}
+  {"id": {{$position}}, "name": "{{$name}}"}
+{{/repeat}}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	methods, err := restclient.Parse(filepath.Join(dir, "api.http"), strings.NewReader(`### Users
+# $file=users.json
+GET /users
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for target, want := range map[string]int{"/users?limit=12": 12, "/users": 3} {
+		var users []map[string]any
+		body := serveBody(server, http.MethodGet, target)
+		if err := json.Unmarshal([]byte(body), &users); err != nil {
+			t.Fatalf("%s body %s is not JSON: %v", target, body, err)
+		}
+		if len(users) != want || users[len(users)-1]["id"] != float64(want) {
+			t.Fatalf("%s returned %d users ending %v, want %d", target, len(users), users[len(users)-1], want)
+		}
+	}
+}
+
+func TestWarnMethodConfigReportsUnbalancedRepeat(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Broken list
+GET /items
+
+[{{#repeat 3}}{}]
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var logs strings.Builder
+	warnMethodConfig(slog.New(slog.NewTextHandler(&logs, nil)), methods)
+	if !strings.Contains(logs.String(), "invalid {{#repeat}} block") {
+		t.Fatalf("logs = %s, want repeat warning", logs.String())
+	}
+}
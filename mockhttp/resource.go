@@ -0,0 +1,271 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+// Methods a $resource collection and its items answer, for Allow headers.
+var (
+	resourceCollectionMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodOptions}
+	resourceItemMethods       = []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+)
+
+// resourceIDField is the JSON field that identifies an item in a $resource
+// collection, and resourceIDParam the path parameter naming it.
+const (
+	resourceIDField = "id"
+	resourceIDParam = "id"
+)
+
+// resourceCollection is the in-memory store behind one $resource section.
+// Items keep insertion order.
+type resourceCollection struct {
+	items []map[string]any
+}
+
+// isResource reports whether a section is a # $resource=name collection.
+func isResource(method *restclient.Method) bool {
+	return method.Variables["resource"] != ""
+}
+
+// resourceItemPath is the item route of a $resource collection path.
+func resourceItemPath(collectionPath string) string {
+	return path.Join(collectionPath, ":"+resourceIDParam)
+}
+
+// matchSectionPath matches requestPath against a section's path, or for a
+// $resource section against its collection path or its item path.
+func matchSectionPath(method *restclient.Method, requestPath string) (map[string]string, bool) {
+	if values, ok := matchPath(method.Path, requestPath); ok || !isResource(method) {
+		return values, ok
+	}
+	return matchPath(resourceItemPath(method.Path), requestPath)
+}
+
+// ResetResources drops every $resource collection, so the next request reseeds
+// it from its $file.
+func (s *Server) ResetResources() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources = make(map[string]*resourceCollection)
+}
+
+// resourceResponse serves a REST request against a $resource collection:
+// GET, POST on the collection and GET, PUT, PATCH, DELETE on an item.
+func (s *Server) resourceResponse(r *http.Request, method *restclient.Method) (int, http.Header, []byte) {
+	headers := method.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	headers.Set("Content-Type", "application/json")
+
+	// Take the id from the item path itself: the matched values also hold
+	// query parameters, and ?id= must not pick a different item.
+	itemValues, isItem := matchPath(resourceItemPath(method.Path), r.URL.Path)
+	if _, ok := matchPath(method.Path, r.URL.Path); ok {
+		isItem = false
+	}
+	id := itemValues[resourceIDParam]
+	allowed := resourceCollectionMethods
+	if isItem {
+		allowed = resourceItemMethods
+	}
+	if r.Method == http.MethodOptions || !slices.Contains(allowed, r.Method) {
+		headers.Set("Allow", strings.Join(allowed, ", "))
+		if r.Method == http.MethodOptions {
+			return http.StatusNoContent, headers, nil
+		}
+		return resourceError(headers, http.StatusMethodNotAllowed, "method not allowed")
+	}
+
+	var input map[string]any
+	if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+		var err error
+		if input, err = decodeResourceItem(r.Body); err != nil {
+			return resourceError(headers, http.StatusBadRequest, err.Error())
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	collection, err := s.resourceCollection(method)
+	if err != nil {
+		s.logResponseRenderError(err)
+		return resourceError(headers, http.StatusInternalServerError, "failed to load resource seed file")
+	}
+
+	if !isItem {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			return resourceJSON(headers, http.StatusOK, collection.items)
+		default: // http.MethodPost
+			if _, ok := input[resourceIDField]; !ok {
+				input[resourceIDField] = collection.nextID()
+			}
+			if !validResourceID(input[resourceIDField]) {
+				return resourceError(headers, http.StatusBadRequest, resourceIDField+" must be a non-empty string or a number")
+			}
+			if collection.find(resourceID(input[resourceIDField])) >= 0 {
+				return resourceError(headers, http.StatusConflict, fmt.Sprintf("%s %v already exists", resourceIDField, input[resourceIDField]))
+			}
+			collection.items = append(collection.items, input)
+			headers.Set("Location", path.Join(r.URL.Path, url.PathEscape(resourceID(input[resourceIDField]))))
+			return resourceJSON(headers, http.StatusCreated, input)
+		}
+	}
+
+	index := collection.find(id)
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if index < 0 {
+			return resourceNotFound(headers, method, id)
+		}
+		return resourceJSON(headers, http.StatusOK, collection.items[index])
+	case http.MethodPut:
+		input[resourceIDField] = collection.idValue(index, id)
+		if index < 0 {
+			collection.items = append(collection.items, input)
+			return resourceJSON(headers, http.StatusCreated, input)
+		}
+		collection.items[index] = input
+		return resourceJSON(headers, http.StatusOK, input)
+	case http.MethodPatch:
+		if index < 0 {
+			return resourceNotFound(headers, method, id)
+		}
+		item := collection.items[index]
+		for name, value := range input {
+			if name != resourceIDField {
+				item[name] = value
+			}
+		}
+		return resourceJSON(headers, http.StatusOK, item)
+	default: // http.MethodDelete
+		if index < 0 {
+			return resourceNotFound(headers, method, id)
+		}
+		collection.items = slices.Delete(collection.items, index, index+1)
+		return http.StatusNoContent, headers, nil
+	}
+}
+
+// resourceCollection returns the store for a section, seeding it from $file
+// on first use. The caller must hold s.mu.
+func (s *Server) resourceCollection(method *restclient.Method) (*resourceCollection, error) {
+	name := method.Variables["resource"]
+	if collection, ok := s.resources[name]; ok {
+		return collection, nil
+	}
+	collection := &resourceCollection{items: []map[string]any{}}
+	if filePath, ok := resolveFilePath(method); ok {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&collection.items); err != nil {
+			return nil, fmt.Errorf("%s: $resource seed must be a JSON array of objects: %w", filePath, err)
+		}
+		if collection.items == nil {
+			collection.items = []map[string]any{}
+		}
+	}
+	s.resources[name] = collection
+	return collection, nil
+}
+
+func (c *resourceCollection) find(id string) int {
+	for i, item := range c.items {
+		if resourceID(item[resourceIDField]) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextID returns one more than the largest integer id in the collection.
+func (c *resourceCollection) nextID() json.Number {
+	highest := int64(0)
+	for _, item := range c.items {
+		if id, err := strconv.ParseInt(resourceID(item[resourceIDField]), 10, 64); err == nil {
+			highest = max(highest, id)
+		}
+	}
+	return json.Number(strconv.FormatInt(highest+1, 10))
+}
+
+// idValue keeps an existing item's id value, or types a new id from the path
+// as a number when it looks like one.
+func (c *resourceCollection) idValue(index int, id string) any {
+	if index >= 0 {
+		return c.items[index][resourceIDField]
+	}
+	if _, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return json.Number(id)
+	}
+	return id
+}
+
+// validResourceID reports whether an item id can be addressed as a path
+// segment: a number or a non-empty string.
+func validResourceID(value any) bool {
+	switch id := value.(type) {
+	case json.Number:
+		return true
+	case string:
+		return id != ""
+	default:
+		return false
+	}
+}
+
+func resourceID(value any) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func decodeResourceItem(body io.Reader) (map[string]any, error) {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	var item map[string]any
+	if err := decoder.Decode(&item); err != nil || item == nil {
+		if errors.Is(err, io.EOF) || err == nil {
+			return nil, errors.New("request body must be a JSON object")
+		}
+		return nil, fmt.Errorf("request body must be a JSON object: %v", err)
+	}
+	return item, nil
+}
+
+func resourceJSON(headers http.Header, status int, value any) (int, http.Header, []byte) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return resourceError(headers, http.StatusInternalServerError, err.Error())
+	}
+	return status, headers, body
+}
+
+func resourceError(headers http.Header, status int, message string) (int, http.Header, []byte) {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return status, headers, body
+}
+
+func resourceNotFound(headers http.Header, method *restclient.Method, id string) (int, http.Header, []byte) {
+	return resourceError(headers, http.StatusNotFound, fmt.Sprintf("%s/%s not found", method.Variables["resource"], id))
+}
@@ -0,0 +1,168 @@
+package mockhttp
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+const usersResource = `### Users
+# $resource=users
+# $file=users.json
+ANY /api/users
+X-Mock: resource
+
+### Current user
+GET /api/users/me
+
+{"id":"me"}
+`
+
+const usersSeed = `[{"id":1,"name":"Ada"},{"id":2,"name":"Linus"}]`
+
+func TestServerResourceItemIDIgnoresQuery(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte(usersSeed), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	methods, err := restclient.Parse(filepath.Join(dir, "api.http"), strings.NewReader(usersResource))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	steps := []struct {
+		method string
+		target string
+		body   string
+		code   int
+		want   string
+	}{
+		{method: http.MethodPatch, target: "/api/users/1?id=2", body: `{"role":"admin"}`, code: http.StatusOK, want: `{"id":1,"name":"Ada","role":"admin"}`},
+		{method: http.MethodPut, target: "/api/users/2?id=1", body: `{"name":"Torvalds"}`, code: http.StatusOK, want: `{"id":2,"name":"Torvalds"}`},
+		{method: http.MethodDelete, target: "/api/users/1?id=2", code: http.StatusNoContent},
+		{method: http.MethodGet, target: "/api/users?id=2", code: http.StatusOK, want: `[{"id":2,"name":"Torvalds"}]`},
+	}
+	for i, step := range steps {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(step.method, step.target, strings.NewReader(step.body)))
+		if response.Code != step.code || response.Body.String() != step.want {
+			t.Fatalf("step %d %s %s = %d %s, want %d %s", i+1, step.method, step.target, response.Code, response.Body.String(), step.code, step.want)
+		}
+	}
+}
+
+func TestServerResourceCRUD(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte(usersSeed), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	methods, err := restclient.Parse(filepath.Join(dir, "api.http"), strings.NewReader(usersResource))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	steps := []struct {
+		method string
+		target string
+		body   string
+		code   int
+		want   string
+	}{
+		{method: http.MethodGet, target: "/api/users", code: http.StatusOK, want: `[{"id":1,"name":"Ada"},{"id":2,"name":"Linus"}]`},
+		{method: http.MethodGet, target: "/api/users/2", code: http.StatusOK, want: `{"id":2,"name":"Linus"}`},
+		{method: http.MethodPost, target: "/api/users", body: `{"name":"Grace"}`, code: http.StatusCreated, want: `{"id":3,"name":"Grace"}`},
+		{method: http.MethodPost, target: "/api/users", body: `{"id":3,"name":"Dup"}`, code: http.StatusConflict, want: `{"error":"id 3 already exists"}`},
+		{method: http.MethodPatch, target: "/api/users/1", body: `{"role":"admin","id":99}`, code: http.StatusOK, want: `{"id":1,"name":"Ada","role":"admin"}`},
+		{method: http.MethodPut, target: "/api/users/2", body: `{"name":"Torvalds"}`, code: http.StatusOK, want: `{"id":2,"name":"Torvalds"}`},
+		{method: http.MethodPut, target: "/api/users/10", body: `{"name":"Ken"}`, code: http.StatusCreated, want: `{"id":10,"name":"Ken"}`},
+		{method: http.MethodDelete, target: "/api/users/1", code: http.StatusNoContent},
+		{method: http.MethodGet, target: "/api/users/1", code: http.StatusNotFound, want: `{"error":"users/1 not found"}`},
+		{method: http.MethodGet, target: "/api/users/me", code: http.StatusOK, want: `{"id":"me"}`},
+		{method: http.MethodPost, target: "/api/users", body: `not json`, code: http.StatusBadRequest},
+		{method: http.MethodPost, target: "/api/users", body: `{"id":{"x":1}}`, code: http.StatusBadRequest, want: `{"error":"id must be a non-empty string or a number"}`},
+		{method: http.MethodPost, target: "/api/users", body: `{"id":""}`, code: http.StatusBadRequest},
+		{method: http.MethodPost, target: "/api/users/2", code: http.StatusMethodNotAllowed},
+		{method: http.MethodGet, target: "/api/users", code: http.StatusOK, want: `[{"id":2,"name":"Torvalds"},{"id":3,"name":"Grace"},{"id":10,"name":"Ken"}]`},
+	}
+	for i, step := range steps {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(step.method, step.target, strings.NewReader(step.body)))
+		if response.Code != step.code {
+			t.Fatalf("step %d %s %s status = %d, want %d (body %s)", i+1, step.method, step.target, response.Code, step.code, response.Body.String())
+		}
+		if step.want != "" && response.Body.String() != step.want {
+			t.Fatalf("step %d %s %s body = %s, want %s", i+1, step.method, step.target, response.Body.String(), step.want)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"name":"Barbara"}`)))
+	if got := response.Header().Get("Location"); got != "/api/users/11" {
+		t.Fatalf("Location = %q, want /api/users/11", got)
+	}
+	if got := response.Header().Get("X-Mock"); got != "resource" {
+		t.Fatalf("X-Mock = %q, want section headers on resource responses", got)
+	}
+
+	response = httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"id":"a b","name":"Space"}`)))
+	location := response.Header().Get("Location")
+	if location != "/api/users/a%20b" {
+		t.Fatalf("Location = %q, want escaped /api/users/a%%20b", location)
+	}
+	if got := serveBody(server, http.MethodGet, location); got != `{"id":"a b","name":"Space"}` {
+		t.Fatalf("GET %s body = %s, want the created item", location, got)
+	}
+
+	response = httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodOptions, "/api/users/2", nil))
+	if response.Code != http.StatusNoContent || response.Header().Get("Allow") != "GET, HEAD, PUT, PATCH, DELETE, OPTIONS" {
+		t.Fatalf("OPTIONS status = %d Allow = %q", response.Code, response.Header().Get("Allow"))
+	}
+}
+
+func TestServerResourceResetReseeds(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte(usersSeed), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	methods, err := restclient.Parse(filepath.Join(dir, "api.http"), strings.NewReader(usersResource))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodDelete, "/api/users/1", nil))
+	if response.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, want 204", response.Code)
+	}
+
+	server.ServeClear(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/clear", nil))
+	response = httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("GET after clear status = %d, want reseeded item", response.Code)
+	}
+}
+
+func TestServerResourceWithoutSeedStartsEmpty(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader("### Notes\n# $resource=notes\nANY /notes\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/notes", nil))
+	if response.Code != http.StatusOK || response.Body.String() != "[]" {
+		t.Fatalf("GET /notes = %d %q, want empty list", response.Code, response.Body.String())
+	}
+}
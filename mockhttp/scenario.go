@@ -0,0 +1,152 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+// ScenarioStarted is the state every scenario begins in, and returns to when
+// it is reset.
+const ScenarioStarted = "Started"
+
+// ScenarioInfo is a JSON-friendly description of a scenario's current state.
+// States lists the states its sections require or move to.
+type ScenarioInfo struct {
+	Name   string   `json:"name"`
+	State  string   `json:"state"`
+	States []string `json:"states,omitempty"`
+}
+
+// scenarioAllows reports whether a section may match given the current
+// scenario states. Sections without $scenario or $requiredState always may.
+func scenarioAllows(method *restclient.Method, states map[string]string) bool {
+	name := method.Variables["scenario"]
+	required, ok := method.Variables["requiredState"]
+	if name == "" || !ok {
+		return true
+	}
+	return scenarioState(states, name) == required
+}
+
+func scenarioState(states map[string]string, name string) string {
+	if state, ok := states[name]; ok {
+		return state
+	}
+	return ScenarioStarted
+}
+
+// advanceScenario applies a served section's $newState.
+func (s *Server) advanceScenario(method *restclient.Method) {
+	name := method.Variables["scenario"]
+	newState, ok := method.Variables["newState"]
+	if name == "" || !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios[name] = newState
+}
+
+// Scenarios returns every scenario declared by the configured sections with
+// its current state, sorted by name.
+func (s *Server) Scenarios() []ScenarioInfo {
+	s.mu.Lock()
+	methods := s.methods
+	states := s.scenarios
+	scenarios := make([]ScenarioInfo, 0)
+	for _, name := range scenarioNames(methods) {
+		scenarios = append(scenarios, ScenarioInfo{
+			Name:   name,
+			State:  scenarioState(states, name),
+			States: scenarioStates(methods, name),
+		})
+	}
+	s.mu.Unlock()
+	return scenarios
+}
+
+// SetScenarioState moves one scenario to state. It fails for a scenario no
+// section declares.
+func (s *Server) SetScenarioState(name, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(scenarioNames(s.methods), name) {
+		return fmt.Errorf("unknown scenario %q", name)
+	}
+	s.scenarios[name] = state
+	return nil
+}
+
+// ResetScenarios returns every scenario to ScenarioStarted.
+func (s *Server) ResetScenarios() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios = make(map[string]string)
+}
+
+// ServeScenarios handles GET of the scenario states and POST to reset them.
+// POST resets every scenario, or with ?scenario=name only that one; adding
+// &state=value moves the scenario to that state instead.
+func (s *Server) ServeScenarios(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.Scenarios())
+	case http.MethodPost:
+		query := r.URL.Query()
+		name := query.Get("scenario")
+		if name == "" {
+			if query.Has("state") {
+				http.Error(w, "state requires a scenario parameter", http.StatusBadRequest)
+				return
+			}
+			s.ResetScenarios()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		state := ScenarioStarted
+		if query.Has("state") {
+			state = query.Get("state")
+		}
+		if err := s.SetScenarioState(name, state); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func scenarioNames(methods []restclient.Method) []string {
+	var names []string
+	for _, method := range methods {
+		if name := method.Variables["scenario"]; name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// scenarioStates lists the states a scenario's sections mention, starting
+// with ScenarioStarted.
+func scenarioStates(methods []restclient.Method, name string) []string {
+	states := []string{ScenarioStarted}
+	for _, method := range methods {
+		if method.Variables["scenario"] != name {
+			continue
+		}
+		for _, key := range []string{"requiredState", "newState"} {
+			if state, ok := method.Variables[key]; ok && !slices.Contains(states, state) {
+				states = append(states, state)
+			}
+		}
+	}
+	return states
+}
@@ -0,0 +1,102 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+const checkoutScenario = `### Empty order
+# $scenario=checkout
+# $requiredState=Started
+GET /order
+
+{"status":"open"}
+
+### Pay
+# $scenario=checkout
+# $newState=paid
+POST /pay
+
+### Paid order
+# $scenario=checkout
+# $requiredState=paid
+GET /order
+
+{"status":"paid"}
+`
+
+func serveBody(server *Server, method, target string) string {
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(method, target, nil))
+	return response.Body.String()
+}
+
+func TestServeScenariosListsAndResetsStates(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(checkoutScenario))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	serveBody(server, http.MethodPost, "/pay")
+
+	response := httptest.NewRecorder()
+	server.ServeScenarios(response, httptest.NewRequest(http.MethodGet, "/scenarios", nil))
+	var scenarios []ScenarioInfo
+	if err := json.Unmarshal(response.Body.Bytes(), &scenarios); err != nil {
+		t.Fatalf("scenarios JSON: %v", err)
+	}
+	want := []ScenarioInfo{{Name: "checkout", State: "paid", States: []string{"Started", "paid"}}}
+	if !reflect.DeepEqual(scenarios, want) {
+		t.Fatalf("scenarios = %#v, want %#v", scenarios, want)
+	}
+
+	for _, tt := range []struct {
+		target string
+		code   int
+		want   string
+	}{
+		{target: "/scenarios", code: http.StatusNoContent, want: ScenarioStarted},
+		{target: "/scenarios?scenario=checkout&state=paid", code: http.StatusNoContent, want: "paid"},
+		{target: "/scenarios?scenario=checkout", code: http.StatusNoContent, want: ScenarioStarted},
+		{target: "/scenarios?scenario=missing", code: http.StatusNotFound, want: ScenarioStarted},
+		{target: "/scenarios?state=paid", code: http.StatusBadRequest, want: ScenarioStarted},
+	} {
+		response := httptest.NewRecorder()
+		server.ServeScenarios(response, httptest.NewRequest(http.MethodPost, tt.target, nil))
+		if response.Code != tt.code {
+			t.Fatalf("POST %s status = %d, want %d", tt.target, response.Code, tt.code)
+		}
+		if got := server.Scenarios()[0].State; got != tt.want {
+			t.Fatalf("after POST %s state = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+
+	response = httptest.NewRecorder()
+	server.ServeScenarios(response, httptest.NewRequest(http.MethodDelete, "/scenarios", nil))
+	if response.Code != http.StatusMethodNotAllowed || response.Header().Get("Allow") != "GET, POST" {
+		t.Fatalf("DELETE status = %d Allow = %q", response.Code, response.Header().Get("Allow"))
+	}
+}
+
+func TestServeClearResetsScenarios(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(checkoutScenario))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	serveBody(server, http.MethodPost, "/pay")
+	server.ServeClear(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/clear", nil))
+	if got := serveBody(server, http.MethodGet, "/order"); got != `{"status":"open"}` {
+		t.Fatalf("order after clear = %q, want open", got)
+	}
+}
@@ -3,7 +3,11 @@ package mockhttp
 import (
 	"context"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,8 +17,14 @@ import (
 
 type Server struct {
 	methods     []restclient.Method
+	precedence  Precedence
 	logger      *slog.Logger
-	counters    map[string]int
+	counters    map[rotationKey]*rotation
+	consumed    map[*restclient.Method]bool
+	scenarios   map[string]string
+	resources   map[string]*resourceCollection
+	seed        uint64
+	seeded      bool
 	events      []RequestEvent
 	subscribers map[chan RequestEvent]struct{}
 	nextEventID atomic.Uint64
@@ -24,8 +34,12 @@ type Server struct {
 func New(methods []restclient.Method, logger *slog.Logger) *Server {
 	s := &Server{
 		methods:     methods,
+		precedence:  PrecedenceSpecific,
 		logger:      logger,
-		counters:    make(map[string]int),
+		counters:    make(map[rotationKey]*rotation),
+		consumed:    make(map[*restclient.Method]bool),
+		scenarios:   make(map[string]string),
+		resources:   make(map[string]*resourceCollection),
 		subscribers: make(map[chan RequestEvent]struct{}),
 	}
 	warnMethodConfig(logger, methods)
@@ -33,15 +47,38 @@ func New(methods []restclient.Method, logger *slog.Logger) *Server {
 }
 
 // SetMethods replaces the mock routes served by this server.
-// Rotation counters are reset so duplicate routes start from the first match again.
+// Rotation counters, consumed $strategy=once sections, scenario states and
+// $resource collections are reset so the new routes start fresh.
 func (s *Server) SetMethods(methods []restclient.Method) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.methods = methods
-	s.counters = make(map[string]int)
+	s.counters = make(map[rotationKey]*rotation)
+	s.consumed = make(map[*restclient.Method]bool)
+	s.scenarios = make(map[string]string)
+	s.resources = make(map[string]*resourceCollection)
 	warnMethodConfig(s.logger, methods)
 }
 
+// SetPrecedence selects how duplicate matches are resolved. The default,
+// PrecedenceSpecific, lets literal path segments beat parameters, parameters
+// beat wildcards, and routes with more request matchers beat fewer.
+func (s *Server) SetPrecedence(precedence Precedence) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.precedence = precedence
+}
+
+// SetSeed makes generated placeholder values reproducible. Each response is
+// seeded from seed, the matched section and the request path (or the request
+// data named by the section's $seedBy).
+func (s *Server) SetSeed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seed = uint64(seed)
+	s.seeded = true
+}
+
 // Methods returns a snapshot of the currently configured mock routes.
 func (s *Server) Methods() []restclient.Method {
 	s.mu.Lock()
@@ -57,11 +94,13 @@ func (s *Server) ClearEvents() {
 	s.events = nil
 }
 
-// ResetCounters resets duplicate-route rotation counters.
+// ResetCounters resets duplicate-route rotation counters and makes consumed
+// $strategy=once sections available again.
 func (s *Server) ResetCounters() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.counters = make(map[string]int)
+	s.counters = make(map[rotationKey]*rotation)
+	s.consumed = make(map[*restclient.Method]bool)
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -69,10 +108,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	requestBody := readRequestBody(r)
 	capture := newResponseCapture(w)
 
-	method, values, ok := s.findMethod(r)
+	method, values, content, ok := s.findMethod(r, requestBody)
 	status := http.StatusNotFound
 	if !ok {
-		http.NotFound(capture, r)
+		s.serveUnmatched(capture, r)
 		status = capture.statusCode()
 		s.logRequest(r, requestBody, capture, status, "", arrivedAt, time.Since(arrivedAt))
 		return
@@ -80,23 +119,48 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !s.delay(r.Context(), method) {
 		return
 	}
-	filePath, hasFile := resolveFilePath(method)
 
-	status = statusFromVariables(s.logger, method.Variables)
-	body, err := renderBody(*method, values, filePath, hasFile)
-	if err != nil {
-		s.logResponseRenderError(err)
-		http.Error(capture, "mock: failed to read response file", http.StatusInternalServerError)
-		s.logRequest(r, requestBody, capture, capture.statusCode(), method.Name, arrivedAt, time.Since(arrivedAt))
-		return
+	var headers http.Header
+	var body []byte
+	if isResource(method) {
+		status, headers, body = s.resourceResponse(r, method)
+	} else if isTemplate(method) {
+		filePath, hasFile := resolveFilePath(method)
+		status = statusFromVariables(s.logger, method.Variables)
+		var err error
+		data := newTemplateData(r, method, values, content)
+		headers, body, err = templateResponse(*method, data, filePath, hasFile, s.responseGenerator(r, method, values, content))
+		if err != nil {
+			s.logResponseRenderError(err)
+			http.Error(capture, "mock: failed to render template", http.StatusInternalServerError)
+			s.logRequest(r, requestBody, capture, capture.statusCode(), method.Name, arrivedAt, time.Since(arrivedAt))
+			return
+		}
+	} else {
+		filePath, hasFile := resolveFilePath(method)
+		status = statusFromVariables(s.logger, method.Variables)
+		var err error
+		gen := s.responseGenerator(r, method, values, content)
+		body, err = renderBody(*method, values, filePath, hasFile, gen)
+		if err != nil {
+			s.logResponseRenderError(err)
+			http.Error(capture, "mock: failed to read response file", http.StatusInternalServerError)
+			s.logRequest(r, requestBody, capture, capture.statusCode(), method.Name, arrivedAt, time.Since(arrivedAt))
+			return
+		}
+		headers = responseHeaders(*method, values, filePath, gen)
 	}
-
-	headers := responseHeaders(*method, values, filePath)
 	for name, headerValues := range headers {
 		for _, value := range headerValues {
 			capture.Header().Add(name, value)
 		}
 	}
+	if r.Method == http.MethodHead {
+		if capture.Header().Get("Content-Length") == "" && statusAllowsBody(status) {
+			capture.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		body = nil
+	}
 	capture.WriteHeader(status)
 	if len(body) > 0 && statusAllowsBody(status) {
 		_, _ = capture.Write(body)
@@ -105,6 +169,24 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.logRequest(r, requestBody, capture, status, method.Name, arrivedAt, time.Since(arrivedAt))
 }
 
+// serveUnmatched answers a request no section matched. When sections exist for
+// the path, OPTIONS gets 204 with an Allow header, and a method none of them
+// accepts gets 405 with one. Anything else, including a request whose method
+// is allowed but whose matchers failed, is 404.
+func (s *Server) serveUnmatched(w http.ResponseWriter, r *http.Request) {
+	allowed := s.allowedMethods(r)
+	if len(allowed) == 0 || r.Method != http.MethodOptions && slices.Contains(allowed, r.Method) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
 func (s *Server) delay(ctx context.Context, method *restclient.Method) bool {
 	raw, ok := method.Variables["delay"]
 	if !ok {
@@ -138,6 +220,11 @@ func warnMethodConfig(logger *slog.Logger, methods []restclient.Method) {
 		logger = slog.Default()
 	}
 	for _, method := range methods {
+		if method.Host != "" {
+			// Full-URL targets used to match any host; say so, or a copied
+			// https://api.example.com/... target silently 404s on localhost.
+			logger.Info("route only matches requests for its host", "host", method.Host, "method", method.Name, "source", method.Source)
+		}
 		if raw, ok := method.Variables["status"]; ok {
 			if _, err := parseStatusCode(raw); err != nil {
 				logger.Warn("invalid $status will be treated as 200", "status", raw, "method", method.Name, "source", method.Source, "error", err)
@@ -148,6 +235,73 @@ func warnMethodConfig(logger *slog.Logger, methods []restclient.Method) {
 				logger.Warn("invalid $delay will be ignored", "delay", raw, "method", method.Name, "source", method.Source, "error", err)
 			}
 		}
+		if raw, ok := method.Variables["strategy"]; ok {
+			if _, err := parseStrategy(raw); err != nil {
+				logger.Warn("invalid $strategy will be treated as rotate", "strategy", raw, "method", method.Name, "source", method.Source, "error", err)
+			}
+		}
+		if method.Variables["scenario"] == "" {
+			for _, name := range []string{"requiredState", "newState"} {
+				if _, ok := method.Variables[name]; ok {
+					logger.Warn("$"+name+" is ignored without $scenario", "method", method.Name, "source", method.Source)
+				}
+			}
+		}
+		for _, err := range placeholderErrors(method) {
+			logger.Warn("invalid placeholder will render empty", "method", method.Name, "source", method.Source, "error", err)
+		}
+		if err := repeatErrors(method); err != nil && !isTemplate(&method) {
+			logger.Warn("invalid {{#repeat}} block will render as text", "method", method.Name, "source", method.Source, "error", err)
+		}
+		if raw, ok := method.Variables["escape"]; ok {
+			if err := parseEscape(raw); err != nil {
+				logger.Warn("invalid $escape will be treated as auto", "escape", raw, "method", method.Name, "source", method.Source, "error", err)
+			}
+		}
+		if raw, ok := method.Variables["template"]; ok {
+			if raw != templateGo {
+				logger.Warn("unknown $template will be ignored (use \"go\")", "template", raw, "method", method.Name, "source", method.Source)
+			} else {
+				for _, err := range templateErrors(method) {
+					logger.Warn("invalid $template=go section", "method", method.Name, "source", method.Source, "error", err)
+				}
+			}
+		}
+		if raw, ok := method.Variables["seed"]; ok {
+			if _, err := parseSeed(raw); err != nil {
+				logger.Warn("invalid $seed will be ignored", "seed", raw, "method", method.Name, "source", method.Source, "error", err)
+			}
+		}
+		if raw, ok := method.Variables["seedBy"]; ok {
+			if _, err := parseSeedBy(raw); err != nil {
+				logger.Warn("invalid $seedBy will be ignored", "seedBy", raw, "method", method.Name, "source", method.Source, "error", err)
+			}
+		}
+		if raw, ok := method.Variables["instanceBy"]; ok {
+			if _, err := parseSeedBy(raw); err != nil {
+				logger.Warn("invalid $instanceBy will be ignored", "instanceBy", raw, "method", method.Name, "source", method.Source, "error", err)
+			}
+		}
+		if raw, ok := method.Variables["rotateBy"]; ok {
+			if _, err := parseRotateBy(raw); err != nil {
+				logger.Warn("invalid $rotateBy will be treated as route", "rotateBy", raw, "method", method.Name, "source", method.Source, "error", err)
+			}
+		}
+		if raw, ok := method.Variables["weight"]; ok {
+			if _, err := parseWeight(raw); err != nil {
+				logger.Warn("invalid $weight will be treated as 1", "weight", raw, "method", method.Name, "source", method.Source, "error", err)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(method.Variables)) {
+			if _, ok := customGenerator(name); ok && !slices.Contains(method.InheritedVariables, name) {
+				logger.Warn("custom variable overrides registered generator {{$"+name+"}}", "variable", "$"+name, "method", method.Name, "source", method.Source)
+			}
+		}
+		// Templates read variables as {{.Vars.name}}, which the {{$name}}
+		// scan cannot see.
+		if isTemplate(&method) {
+			continue
+		}
 		for _, name := range restclient.UnusedCustomVariables(method) {
 			logger.Warn("unused custom variable (not referenced as {{$"+name+"}} in body or response headers)",
 				"variable", "$"+name,
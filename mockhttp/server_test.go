@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -181,6 +183,303 @@ Content-Type: application/json
 	}
 }
 
+func TestServerRotationCountersFollowRouteGroup(t *testing.T) {
+	parse := func(rotateBy string) []restclient.Method {
+		t.Helper()
+		methods, err := restclient.Parse("test.http", strings.NewReader(`### A
+`+rotateBy+`
+GET /users/:id
+
+A
+
+### B
+GET /users/:id
+
+B
+`))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		return methods
+	}
+	get := func(server *Server, target, client string) string {
+		t.Helper()
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set("X-Client-Id", client)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response.Body.String()
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name     string
+		rotateBy string
+		requests [][2]string
+		want     string
+	}{
+		{
+			name:     "route group by default",
+			requests: [][2]string{{"/users/1", ""}, {"/users/2", ""}, {"/users/1?cb=123", ""}},
+			want:     "ABA",
+		},
+		{
+			name:     "path",
+			rotateBy: "# $rotateBy=path",
+			requests: [][2]string{{"/users/1", ""}, {"/users/2", ""}, {"/users/1?cb=123", ""}},
+			want:     "AAB",
+		},
+		{
+			name:     "header",
+			rotateBy: "# $rotateBy=header:X-Client-Id",
+			requests: [][2]string{{"/users/1", "web"}, {"/users/2", "ios"}, {"/users/3", "web"}},
+			want:     "AAB",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := New(parse(tt.rotateBy), logger)
+			var got strings.Builder
+			for _, request := range tt.requests {
+				got.WriteString(get(server, request[0], request[1]))
+			}
+			if got.String() != tt.want {
+				t.Fatalf("bodies = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestServerScenarioStateTransitions(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(checkoutScenario))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	steps := []struct {
+		method string
+		target string
+		want   string
+	}{
+		{method: http.MethodGet, target: "/order", want: `{"status":"open"}`},
+		{method: http.MethodGet, target: "/order", want: `{"status":"open"}`},
+		{method: http.MethodPost, target: "/pay"},
+		{method: http.MethodGet, target: "/order", want: `{"status":"paid"}`},
+		{method: http.MethodGet, target: "/order", want: `{"status":"paid"}`},
+	}
+	for i, step := range steps {
+		if got := serveBody(server, step.method, step.target); got != step.want {
+			t.Fatalf("step %d %s %s body = %q, want %q", i+1, step.method, step.target, got, step.want)
+		}
+	}
+
+	methods, err = restclient.Parse("test.http", strings.NewReader(checkoutScenario))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server.SetMethods(methods)
+	if got := serveBody(server, http.MethodGet, "/order"); got != `{"status":"open"}` {
+		t.Fatalf("order after reload = %q, want scenario reset", got)
+	}
+}
+
+func TestServerSequenceThenStickStrategy(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### First failure
+# $strategy=sequence-then-stick
+# $status=503
+GET /flaky
+
+### Second failure
+# $status=503
+GET /flaky
+
+### Recovered
+GET /flaky
+
+ok
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i, want := range []int{503, 503, 200, 200, 200} {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/flaky", nil))
+		if response.Code != want {
+			t.Fatalf("request %d status = %d, want %d", i+1, response.Code, want)
+		}
+	}
+
+	server.ResetCounters()
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/flaky", nil))
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("after reset status = %d, want 503", response.Code)
+	}
+}
+
+func TestServerOnceStrategyFallsThrough(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Fallback
+GET /users/:id
+
+fallback
+
+### Welcome
+# $strategy=once
+GET /users/me
+
+welcome
+
+### Profile
+GET /users/me
+
+profile
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i, want := range []string{"welcome", "profile", "profile"} {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+		if response.Body.String() != want {
+			t.Fatalf("request %d body = %q, want %q", i+1, response.Body.String(), want)
+		}
+	}
+
+	methods, err = restclient.Parse("test.http", strings.NewReader(`### Fallback
+GET /users/:id
+
+fallback
+
+### Welcome
+# $strategy=once
+GET /users/me
+
+welcome
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server.SetMethods(methods)
+	for i, want := range []string{"welcome", "fallback"} {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+		if response.Body.String() != want {
+			t.Fatalf("after reload request %d body = %q, want %q", i+1, response.Body.String(), want)
+		}
+	}
+}
+
+func TestServerServesOnceSectionToOneConcurrentRequest(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Welcome
+# $strategy=once
+GET /users/me
+
+welcome
+
+### Profile
+GET /users/me
+
+profile
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	welcomes := 0
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+			if response.Body.String() == "welcome" {
+				mu.Lock()
+				welcomes++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if welcomes != 1 {
+		t.Fatalf("welcome served %d times, want 1", welcomes)
+	}
+}
+
+func TestServerWeightedAndRandomStrategies(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Usually ok
+# $strategy=weighted
+# $weight=1
+GET /weighted
+
+ok
+
+### Never
+# $weight=0
+GET /weighted
+
+never
+
+### Heads
+# $strategy=random
+GET /coin
+
+heads
+
+### Tails
+GET /coin
+
+tails
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	seen := make(map[string]bool)
+	for range 100 {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/weighted", nil))
+		if response.Body.String() != "ok" {
+			t.Fatalf("weighted body = %q, want zero-weight section skipped", response.Body.String())
+		}
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/coin", nil))
+		seen[response.Body.String()] = true
+	}
+	if len(seen) != 2 || !seen["heads"] || !seen["tails"] {
+		t.Fatalf("random bodies = %v, want heads and tails", seen)
+	}
+}
+
+func TestWarnMethodConfigInvalidStrategy(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Users
+# $strategy=sticky
+# $weight=heavy
+GET /users
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var buf bytes.Buffer
+	_ = New(methods, slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	for _, want := range []string{"invalid $strategy", "invalid $weight"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("log = %q, want %q", buf.String(), want)
+		}
+	}
+	if strings.Contains(buf.String(), "unused custom variable") {
+		t.Fatalf("log = %q, want $strategy and $weight treated as control variables", buf.String())
+	}
+}
+
 func TestServerRequestEventIncludesRequestAndResponseBodies(t *testing.T) {
 	methods, err := restclient.Parse("test.http", strings.NewReader(`### Create User
 # $status=201
@@ -500,6 +799,49 @@ ok
 	}
 }
 
+func TestWarnMethodConfigLogsHostBoundRoutes(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### GitHub users
+GET https://api.github.com/users
+
+[]
+
+### Local users
+GET http://localhost:8080/local
+
+[]
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var buf bytes.Buffer
+	warnMethodConfig(slog.New(slog.NewTextHandler(&buf, nil)), methods)
+	logText := buf.String()
+	if !strings.Contains(logText, "route only matches requests for its host") || !strings.Contains(logText, "host=api.github.com") {
+		t.Fatalf("log = %q, want host-bound route for api.github.com", logText)
+	}
+	if strings.Contains(logText, "Local users") {
+		t.Fatalf("log = %q, want no entry for localhost route", logText)
+	}
+}
+
+func TestWarnMethodConfigInvalidGeneratorArguments(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Bad range
+GET /numbers
+X-Pick: {{$oneOf()}}
+
+{{$integer(9,1)}} {{$integer(1,9)}} {{$nope:order}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var buf bytes.Buffer
+	_ = New(methods, slog.New(slog.NewTextHandler(&buf, nil)))
+	logText := buf.String()
+	if strings.Count(logText, "invalid placeholder") != 3 || !strings.Contains(logText, "integer(9,1)") {
+		t.Fatalf("log = %q, want warnings for integer(9,1), oneOf() and nope:order", logText)
+	}
+}
+
 func TestServerHeaderMatchingAndPlaceholderHeaders(t *testing.T) {
 	methods, err := restclient.Parse("test.http", strings.NewReader(`### Secured
 # $header.Authorization=Bearer secret
@@ -537,6 +879,521 @@ ok-{{$id}}
 	}
 }
 
+func TestServerMatchesJSONBodyFields(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Gold order
+# $body.json.customer.tier=gold
+POST /orders
+
+gold
+
+### Bulk order
+# $body.json.items[0].sku=BULK
+POST /orders
+
+bulk
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, tt := range []struct {
+		body string
+		code int
+		want string
+	}{
+		{body: `{"customer":{"tier":"gold"}}`, code: http.StatusOK, want: "gold"},
+		{body: `{"items":[{"sku":"BULK"}]}`, code: http.StatusOK, want: "bulk"},
+		{body: `{"customer":{"tier":"silver"}}`, code: http.StatusNotFound},
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.body))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		if response.Code != tt.code {
+			t.Fatalf("body %s status = %d, want %d", tt.body, response.Code, tt.code)
+		}
+		if tt.want != "" && response.Body.String() != tt.want {
+			t.Fatalf("body %s response = %q, want %q", tt.body, response.Body.String(), tt.want)
+		}
+	}
+
+	// The matcher reads the captured body, so the request log still shows it.
+	last := server.events[len(server.events)-1]
+	if !strings.Contains(last.Request.Details, `"tier":"silver"`) {
+		t.Fatalf("request details = %q, want logged request body", last.Request.Details)
+	}
+}
+
+func TestServerMatchesJSONBodyFieldsPastLoggedBody(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Gold order
+# $body.json.customer.tier=gold
+POST /orders
+
+gold
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	body := `{"note":"` + strings.Repeat("x", maxLoggedBodyBytes) + `","customer":{"tier":"gold"}}`
+	request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	if response.Code != http.StatusOK || response.Body.String() != "gold" {
+		t.Fatalf("response = %d %q, want 200 gold", response.Code, response.Body.String())
+	}
+	remaining, _ := io.ReadAll(request.Body)
+	if string(remaining) != body {
+		t.Fatalf("request body after match has %d bytes, want %d", len(remaining), len(body))
+	}
+}
+
+func TestServerServesFileInheritedFromImportedBase(t *testing.T) {
+	dir := t.TempDir()
+	common := filepath.Join(dir, "common")
+	if err := os.Mkdir(common, 0o700); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	files := map[string]string{
+		filepath.Join(common, "base.http"):  "### Base\n# $file=users.json\nGET /base\n",
+		filepath.Join(common, "users.json"): `[{"id":1}]`,
+		filepath.Join(dir, "api.http"):      "# @import common/base.http\n\n### Users\n# $extends=Base\nGET /users\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	methods, err := restclient.Load([]string{filepath.Join(dir, "api.http")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, path := range []string{"/base", "/users"} {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		if response.Code != http.StatusOK || response.Body.String() != `[{"id":1}]` {
+			t.Fatalf("GET %s = %d %q, want 200 users.json", path, response.Code, response.Body.String())
+		}
+	}
+}
+
+func TestServerQueryMatcherOperators(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Debug listing
+# $query.debug=*
+GET /items
+
+debug
+
+### Sorted listing
+# $query.sort=~^(asc|desc)$
+# $query.debug=!
+GET /items
+
+sorted {{$sort}}
+
+### Plain listing
+# $query.sort=!
+GET /items
+
+plain
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, tt := range []struct {
+		target string
+		code   int
+		want   string
+	}{
+		{target: "/items?debug", code: http.StatusOK, want: "debug"},
+		{target: "/items?sort=desc", code: http.StatusOK, want: "sorted desc"},
+		{target: "/items", code: http.StatusOK, want: "plain"},
+		{target: "/items?sort=name", code: http.StatusNotFound},
+	} {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if response.Code != tt.code {
+			t.Fatalf("%s status = %d, want %d", tt.target, response.Code, tt.code)
+		}
+		if tt.want != "" && response.Body.String() != tt.want {
+			t.Fatalf("%s response = %q, want %q", tt.target, response.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestServerMatchesCookiesAndExpandsCookiePlaceholders(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Signed in
+# $cookie.session=*
+GET /me
+X-Session: {{$cookie.session}}
+
+{"session":"{{$cookie.session}}","theme":"{{$cookie.theme}}"}
+
+### Signed out
+# $status=401
+GET /me
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	request := httptest.NewRequest(http.MethodGet, "/me", nil)
+	request.Header.Set("Cookie", "theme=dark; session=abc123")
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	if response.Code != http.StatusOK || response.Body.String() != `{"session":"abc123","theme":"dark"}` {
+		t.Fatalf("signed in = %d %q", response.Code, response.Body.String())
+	}
+	if got := response.Header().Get("X-Session"); got != "abc123" {
+		t.Fatalf("X-Session = %q, want abc123", got)
+	}
+
+	response = httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/me", nil))
+	if response.Code != http.StatusUnauthorized {
+		t.Fatalf("signed out status = %d, want 401", response.Code)
+	}
+}
+
+func TestServerExpandsRequestPlaceholders(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Echo
+POST /orders/:id
+X-Request-Id: {{$request.header.x-request-id}}
+
+{"method":"{{$request.method}}","path":"{{$request.path}}","page":"{{$request.query.page}}","name":"{{$request.json.user.name}}","qty":{{$request.json.items.0.qty}},"user":{{$request.json.user}},"missing":"{{$request.json.nope}}"}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	request := httptest.NewRequest(http.MethodPost, "/orders/7?page=2&page=3", strings.NewReader(`{"user":{"name":"Ada"},"items":[{"qty":3}]}`))
+	request.Header.Set("X-Request-ID", "req-42")
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+
+	want := `{"method":"POST","path":"/orders/7","page":"2","name":"Ada","qty":3,"user":{"name":"Ada"},"missing":""}`
+	if got := response.Body.String(); got != want {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+	if got := response.Header().Get("X-Request-Id"); got != "req-42" {
+		t.Fatalf("X-Request-Id = %q, want req-42", got)
+	}
+}
+
+func TestServerExpandsRawRequestBody(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Echo
+POST /echo
+
+got {{$request.body}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("a=1&b=2")))
+	if got := response.Body.String(); got != "got a=1&b=2" {
+		t.Fatalf("body = %q, want %q", got, "got a=1&b=2")
+	}
+}
+
+func TestServerReadsLargeRequestBodyOnlyForPlaceholders(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Upload
+POST /upload
+
+ok
+
+### Echo
+POST /echo
+
+{{$request.body | sha256}} {{$request.json.tail}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var logs bytes.Buffer
+	server := New(methods, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	huge := strings.Repeat("x", maxMatchBodyBytes+1)
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(huge)))
+	if response.Body.String() != "ok" {
+		t.Fatalf("upload body = %q, want ok", response.Body.String())
+	}
+	if strings.Contains(logs.String(), "too large") {
+		t.Fatalf("logs = %s, want no body size warning for a section without body placeholders", logs.String())
+	}
+
+	body := `{"pad":"` + strings.Repeat("x", maxLoggedBodyBytes) + `","tail":"end"}`
+	response = httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body)))
+	if want := sha256Hex(body) + " end"; response.Body.String() != want {
+		t.Fatalf("echo body = %q, want %q", response.Body.String(), want)
+	}
+}
+
+func TestServerMatchesFormFieldsWithoutConsumingBody(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Admin login
+# $form.username=admin
+POST /login
+
+admin
+
+### Guest login
+# $form.username=guest
+POST /login
+
+guest
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	request := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("username=admin&password=x"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	if response.Body.String() != "admin" {
+		t.Fatalf("body = %q, want admin", response.Body.String())
+	}
+	remaining, _ := io.ReadAll(request.Body)
+	if string(remaining) != "username=admin&password=x" {
+		t.Fatalf("request body after match = %q, want replayable body", remaining)
+	}
+}
+
+func TestServerMatchesMultipartFormPastLoggedBody(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Avatar upload
+# $form.avatar.filename=me.png
+# $form.username=admin
+POST /upload
+
+uploaded
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	file, _ := writer.CreateFormFile("avatar", "me.png")
+	file.Write(bytes.Repeat([]byte{0x89}, maxLoggedBodyBytes+1024))
+	writer.WriteField("username", "admin")
+	writer.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body.Bytes()))
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	if response.Code != http.StatusOK || response.Body.String() != "uploaded" {
+		t.Fatalf("response = %d %q, want 200 uploaded", response.Code, response.Body.String())
+	}
+}
+
+func TestServerRegexPathParametersSelectRoute(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Numeric user
+GET /users/:id(\d+)
+
+user-{{$id}}
+
+### Named user
+GET /users/:name([a-z]+)
+
+named-{{$name}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for path, want := range map[string]string{"/users/42": "user-42", "/users/me": "named-me"} {
+		for range 2 {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+			if response.Body.String() != want {
+				t.Fatalf("GET %s body = %q, want %q", path, response.Body.String(), want)
+			}
+		}
+	}
+}
+
+func TestServerCatchAllPathPlaceholder(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### CDN
+GET /assets/**
+X-Asset: {{$rest}}
+
+asset={{$rest}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/assets/img/logo.svg", nil))
+	if response.Body.String() != "asset=img/logo.svg" {
+		t.Fatalf("body = %q, want asset=img/logo.svg", response.Body.String())
+	}
+	if got := response.Header().Get("X-Asset"); got != "img/logo.svg" {
+		t.Fatalf("X-Asset = %q, want img/logo.svg", got)
+	}
+}
+
+func TestServerPrefersMostSpecificRoute(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Any user
+GET /users/:id
+
+user-{{$id}}
+
+### Current user
+GET /users/me
+
+me
+
+### Current user again
+GET /users/me
+
+me-again
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for i, want := range []string{"me", "me-again", "me"} {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+		if response.Body.String() != want {
+			t.Fatalf("request %d body = %q, want %q", i+1, response.Body.String(), want)
+		}
+	}
+
+	server.SetPrecedence(PrecedenceRotate)
+	server.ResetCounters()
+	for i, want := range []string{"user-me", "me", "me-again"} {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+		if response.Body.String() != want {
+			t.Fatalf("rotate request %d body = %q, want %q", i+1, response.Body.String(), want)
+		}
+	}
+}
+
+func TestServerAnyMethodHeadOptionsAndMethodNotAllowed(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Get user
+GET /users/:id
+Content-Type: application/json
+
+{"id":"{{$id}}"}
+
+### Delete user
+# $status=204
+DELETE /users/:id
+
+### Health
+ANY /health
+
+ok
+
+### Health post
+POST /health
+
+posted
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, tt := range []struct {
+		method string
+		target string
+		code   int
+		body   string
+		allow  string
+	}{
+		{method: http.MethodPut, target: "/health", code: http.StatusOK, body: "ok"},
+		{method: http.MethodPost, target: "/health", code: http.StatusOK, body: "posted"},
+		{method: http.MethodHead, target: "/users/7", code: http.StatusOK},
+		{method: http.MethodOptions, target: "/users/7", code: http.StatusNoContent, allow: "GET, HEAD, DELETE, OPTIONS"},
+		{method: http.MethodPatch, target: "/users/7", code: http.StatusMethodNotAllowed, allow: "GET, HEAD, DELETE, OPTIONS"},
+		{method: http.MethodPatch, target: "/projects/7", code: http.StatusNotFound},
+	} {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(tt.method, tt.target, nil))
+		if response.Code != tt.code {
+			t.Fatalf("%s %s status = %d, want %d", tt.method, tt.target, response.Code, tt.code)
+		}
+		if tt.body != "" && response.Body.String() != tt.body {
+			t.Fatalf("%s %s body = %q, want %q", tt.method, tt.target, response.Body.String(), tt.body)
+		}
+		if got := response.Header().Get("Allow"); got != tt.allow {
+			t.Fatalf("%s %s Allow = %q, want %q", tt.method, tt.target, got, tt.allow)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodHead, "/users/7", nil))
+	if response.Body.Len() != 0 {
+		t.Fatalf("HEAD body = %q, want headers only", response.Body.String())
+	}
+	if response.Header().Get("Content-Type") != "application/json" || response.Header().Get("Content-Length") != "10" {
+		t.Fatalf("HEAD headers = %#v, want GET headers and Content-Length", response.Header())
+	}
+}
+
+func TestServerMatchesVirtualHosts(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### API user
+GET https://api.example.test/users/:id
+
+api {{$id}}
+
+### CDN asset
+GET https://*.cdn.example.test/**
+
+cdn {{$rest}}
+
+### Fallback user
+GET /users/:id
+
+fallback {{$id}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, tt := range []struct {
+		host   string
+		target string
+		code   int
+		want   string
+	}{
+		{host: "api.example.test:8080", target: "/users/7", code: http.StatusOK, want: "api 7"},
+		{host: "localhost:8080", target: "/users/7", code: http.StatusOK, want: "fallback 7"},
+		{host: "eu.cdn.example.test", target: "/img/logo.png", code: http.StatusOK, want: "cdn img/logo.png"},
+		{host: "api.example.test", target: "/img/logo.png", code: http.StatusNotFound},
+	} {
+		request := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		request.Host = tt.host
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		if response.Code != tt.code {
+			t.Fatalf("%s%s status = %d, want %d", tt.host, tt.target, response.Code, tt.code)
+		}
+		if tt.want != "" && response.Body.String() != tt.want {
+			t.Fatalf("%s%s body = %q, want %q", tt.host, tt.target, response.Body.String(), tt.want)
+		}
+	}
+}
+
 func TestServerSetMethodsConcurrentWithServeHTTP(t *testing.T) {
 	methods, err := restclient.Parse("test.http", strings.NewReader(`### A
 GET /a
@@ -645,7 +1502,7 @@ func BenchmarkServerRouteLookup(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, _, ok := server.findMethod(request); !ok {
+		if _, _, _, ok := server.findMethod(request, loggedBody{}); !ok {
 			b.Fatal("findMethod() did not match route")
 		}
 	}
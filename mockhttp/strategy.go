@@ -0,0 +1,167 @@
+package mockhttp
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+// Strategy selects how a request chooses among equally matching sections.
+// Sections opt in with # $strategy=name; the first candidate that declares one
+// sets the strategy for the group.
+type Strategy string
+
+const (
+	// StrategyRotate cycles through the candidates in order (the default).
+	StrategyRotate Strategy = "rotate"
+	// StrategyRandom picks a candidate uniformly at random.
+	StrategyRandom Strategy = "random"
+	// StrategyWeighted picks at random in proportion to each section's $weight.
+	StrategyWeighted Strategy = "weighted"
+	// StrategySequenceThenStick serves the candidates in order, then keeps
+	// serving the last one.
+	StrategySequenceThenStick Strategy = "sequence-then-stick"
+	// StrategyOnce serves each section a single time; a consumed section no
+	// longer matches, so later requests fall through to the next match.
+	StrategyOnce Strategy = "once"
+)
+
+// parseStrategy validates a $strategy value.
+func parseStrategy(raw string) (Strategy, error) {
+	switch strategy := Strategy(raw); strategy {
+	case StrategyRotate, StrategyRandom, StrategyWeighted, StrategySequenceThenStick, StrategyOnce:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown strategy %q (use %s, %s, %s, %s or %s)", raw,
+			StrategyRotate, StrategyRandom, StrategyWeighted, StrategySequenceThenStick, StrategyOnce)
+	}
+}
+
+// parseWeight validates a $weight value: a non-negative integer.
+func parseWeight(raw string) (int, error) {
+	weight, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, err
+	}
+	if weight < 0 {
+		return 0, fmt.Errorf("weight %d is negative", weight)
+	}
+	return weight, nil
+}
+
+// methodStrategy returns a section's $strategy, and false when it declares
+// none or an invalid one (warnMethodConfig reports those at load).
+func methodStrategy(method *restclient.Method) (Strategy, bool) {
+	raw, ok := method.Variables["strategy"]
+	if !ok {
+		return "", false
+	}
+	strategy, err := parseStrategy(raw)
+	return strategy, err == nil
+}
+
+// groupStrategy returns the strategy declared by the first candidate that has one.
+func groupStrategy(matches []routeMatch) Strategy {
+	for _, match := range matches {
+		if strategy, ok := methodStrategy(match.method); ok {
+			return strategy
+		}
+	}
+	return StrategyRotate
+}
+
+// methodWeight returns a section's $weight, defaulting to 1.
+func methodWeight(method *restclient.Method) int {
+	raw, ok := method.Variables["weight"]
+	if !ok {
+		return 1
+	}
+	weight, err := parseWeight(raw)
+	if err != nil {
+		return 1
+	}
+	return weight
+}
+
+// weightedIndex picks a candidate in proportion to its weight. When every
+// weight is zero it returns the first candidate.
+func weightedIndex(matches []routeMatch) int {
+	total := 0
+	for _, match := range matches {
+		total += methodWeight(match.method)
+	}
+	if total == 0 {
+		return 0
+	}
+	pick := rand.IntN(total)
+	for i, match := range matches {
+		pick -= methodWeight(match.method)
+		if pick < 0 {
+			return i
+		}
+	}
+	return len(matches) - 1
+}
+
+// rotationKey identifies a rotation counter: the candidate group (its route
+// indexes) and the $rotateBy scope within it.
+type rotationKey struct {
+	group string
+	scope string
+}
+
+// rotation is the counter for one rotationKey. stick is set for
+// $strategy=sequence-then-stick, whose count stops at the last section.
+type rotation struct {
+	sections []int
+	count    int
+	stick    bool
+}
+
+// next returns the position in sections that serves the next request.
+func (r *rotation) next() int {
+	if r.stick {
+		return min(r.count, len(r.sections)-1)
+	}
+	return r.count % len(r.sections)
+}
+
+// parseRotateBy validates a $rotateBy value: "route", "path" or "header:Name".
+func parseRotateBy(raw string) (string, error) {
+	switch raw {
+	case "route", "path":
+		return raw, nil
+	}
+	if name, ok := strings.CutPrefix(raw, "header:"); ok && strings.TrimSpace(name) != "" {
+		return raw, nil
+	}
+	return "", fmt.Errorf(`unknown rotateBy %q (use "route", "path" or "header:Name")`, raw)
+}
+
+// rotationScope returns the part of the counter key selected by the first
+// candidate's $rotateBy: the request path, a request header value, or nothing
+// for "route" (the default), which shares one counter per candidate group.
+func rotationScope(r *http.Request, matches []routeMatch) string {
+	for _, match := range matches {
+		raw, ok := match.method.Variables["rotateBy"]
+		if !ok {
+			continue
+		}
+		rotateBy, err := parseRotateBy(raw)
+		if err != nil {
+			continue
+		}
+		if rotateBy == "path" {
+			return "path " + r.URL.Path
+		}
+		if name, ok := strings.CutPrefix(rotateBy, "header:"); ok {
+			return "header " + r.Header.Get(strings.TrimSpace(name))
+		}
+		return ""
+	}
+	return ""
+}
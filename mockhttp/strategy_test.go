@@ -0,0 +1,49 @@
+package mockhttp
+
+import (
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+func TestParseStrategy(t *testing.T) {
+	for _, raw := range []string{"rotate", "random", "weighted", "sequence-then-stick", "once"} {
+		if _, err := parseStrategy(raw); err != nil {
+			t.Fatalf("parseStrategy(%q) error = %v", raw, err)
+		}
+	}
+	if _, err := parseStrategy("sticky"); err == nil {
+		t.Fatal(`parseStrategy("sticky") error = nil`)
+	}
+}
+
+func TestGroupStrategyUsesFirstDeclaration(t *testing.T) {
+	matches := []routeMatch{
+		{method: &restclient.Method{Variables: map[string]string{}}},
+		{method: &restclient.Method{Variables: map[string]string{"strategy": "random"}}},
+		{method: &restclient.Method{Variables: map[string]string{"strategy": "once"}}},
+	}
+	if got := groupStrategy(matches); got != StrategyRandom {
+		t.Fatalf("groupStrategy() = %q, want %q", got, StrategyRandom)
+	}
+	if got := groupStrategy(matches[:1]); got != StrategyRotate {
+		t.Fatalf("groupStrategy() = %q, want default %q", got, StrategyRotate)
+	}
+}
+
+func TestWeightedIndexSkipsZeroWeights(t *testing.T) {
+	matches := []routeMatch{
+		{method: &restclient.Method{Variables: map[string]string{"weight": "0"}}},
+		{method: &restclient.Method{Variables: map[string]string{"weight": "3"}}},
+		{method: &restclient.Method{Variables: map[string]string{"weight": "0"}}},
+	}
+	for range 50 {
+		if got := weightedIndex(matches); got != 1 {
+			t.Fatalf("weightedIndex() = %d, want 1", got)
+		}
+	}
+	matches[1].method.Variables["weight"] = "0"
+	if got := weightedIndex(matches); got != 0 {
+		t.Fatalf("weightedIndex() with all zero weights = %d, want 0", got)
+	}
+}
@@ -0,0 +1,222 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+// templateGo is the $template value that renders a section with text/template.
+const templateGo = "go"
+
+// templateData is the dot value of a $template=go body or header.
+type templateData struct {
+	// Method is the request method, and Path the request path.
+	Method string
+	Path   string
+	// Params holds path parameters, the first value of each query parameter,
+	// and cookies as "cookie.name", the same values {{$name}} placeholders see.
+	Params  map[string]string
+	Query   url.Values
+	Headers http.Header
+	Cookies map[string]string
+	// Body is the raw request body, and JSON the body decoded as JSON (nil
+	// when it is not JSON).
+	Body string
+	JSON any
+	// Vars holds the section's variables.
+	Vars map[string]string
+}
+
+// isTemplate reports whether a section renders with text/template.
+func isTemplate(method *restclient.Method) bool {
+	return method.Variables["template"] == templateGo
+}
+
+func newTemplateData(r *http.Request, method *restclient.Method, values map[string]string, content *requestContent) templateData {
+	cookies := make(map[string]string)
+	for _, cookie := range r.Cookies() {
+		if _, ok := cookies[cookie.Name]; !ok {
+			cookies[cookie.Name] = cookie.Value
+		}
+	}
+	body, _ := content.text()
+	decoded, _ := content.jsonValue()
+	return templateData{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Params:  values,
+		Query:   r.URL.Query(),
+		Headers: r.Header,
+		Cookies: cookies,
+		Body:    body,
+		JSON:    decoded,
+		Vars:    method.Variables,
+	}
+}
+
+// templateResponse renders a $template=go section's body (inline or $file)
+// and response headers.
+func templateResponse(method restclient.Method, data templateData, filePath string, hasFile bool, gen *generator) (http.Header, []byte, error) {
+	text := method.Body
+	if text == "" && hasFile {
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		if !isMostlyText(raw) {
+			return nil, nil, fmt.Errorf("%s: $template=go needs a text file", filePath)
+		}
+		text = string(raw)
+	}
+	body, err := executeTemplate(text, data, gen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("section %q body: %w", method.Name, err)
+	}
+
+	headers := method.Headers.Clone()
+	for name, headerValues := range headers {
+		for i, value := range headerValues {
+			rendered, err := executeTemplate(value, data, gen)
+			if err != nil {
+				return nil, nil, fmt.Errorf("section %q header %s: %w", method.Name, name, err)
+			}
+			headerValues[i] = rendered
+		}
+	}
+	setFileContentType(headers, method, filePath)
+	return headers, []byte(body), nil
+}
+
+// parsedTemplates caches compiled templates by their text.
+var parsedTemplates textCache[*template.Template]
+
+func parseTemplate(text string) (*template.Template, error) {
+	if cached, ok := parsedTemplates.load(text); ok {
+		return cached, nil
+	}
+	tmpl, err := template.New("mock").Funcs(templateFuncs(newGenerator())).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	parsedTemplates.store(text, tmpl)
+	return tmpl, nil
+}
+
+// executeTemplate renders text with the generator functions bound to gen.
+func executeTemplate(text string, data templateData, gen *generator) (string, error) {
+	parsed, err := parseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := parsed.Clone()
+	if err != nil {
+		return "", err
+	}
+	tmpl.Funcs(templateFuncs(gen))
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// templateErrors returns the parse errors in a $template=go section's inline
+// body and response headers, for load-time warnings.
+func templateErrors(method restclient.Method) []error {
+	var errs []error
+	if _, err := parseTemplate(method.Body); err != nil {
+		errs = append(errs, err)
+	}
+	for _, values := range method.Headers {
+		for _, value := range values {
+			if _, err := parseTemplate(value); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// templateBuiltins are the functions text/template predefines.
+var templateBuiltins = []string{
+	"and", "call", "eq", "ge", "gt", "html", "index", "js", "le", "len", "lt",
+	"ne", "not", "or", "print", "printf", "println", "slice", "urlquery",
+}
+
+// isTemplateFunc reports whether name is a text/template builtin or one of
+// the helpers templateHelpers defines, which generators must not shadow.
+func isTemplateFunc(name string) bool {
+	_, ok := templateHelpers(nil)[name]
+	return ok || slices.Contains(templateBuiltins, name)
+}
+
+// templateFuncs are the helpers available to $template=go sections plus each
+// generator by name ({{uuid}}, {{integer 1 100}}).
+func templateFuncs(gen *generator) template.FuncMap {
+	funcs := templateHelpers(gen)
+	for _, name := range slices.Concat(generatorNames, customGeneratorNames()) {
+		funcs[name] = func(args ...any) (string, error) {
+			return gen.generate(name, templateArgs(args))
+		}
+	}
+	return funcs
+}
+
+// templateHelpers are json, default, the placeholder filters (upper, lower,
+// urlencode, base64, sha256, truncate N) and fake "name" args....
+func templateHelpers(gen *generator) template.FuncMap {
+	return template.FuncMap{
+		"json": func(value any) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+		"default": func(fallback, value any) any {
+			if isEmptyValue(value) {
+				return fallback
+			}
+			return value
+		},
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"urlencode": url.QueryEscape,
+		"base64":    base64Encode,
+		"sha256":    sha256Hex,
+		"truncate":  truncate,
+		"fake": func(name string, args ...any) (string, error) {
+			return gen.generate(name, templateArgs(args))
+		},
+	}
+}
+
+// templateArgs formats template function arguments, so {{integer 1 100}}
+// and {{integer "1" "100"}} are the same call.
+func templateArgs(args []any) []string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = fmt.Sprint(arg)
+	}
+	return formatted
+}
+
+func isEmptyValue(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,154 @@
+package mockhttp
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sspencer/mock/restclient"
+)
+
+func TestTemplateRendersRequestData(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Search
+# $template=go
+# $status=201
+POST /tenants/:tenant/search
+X-Method: {{.Method}}
+X-Tenant: {{.Params.tenant | upper}}
+
+{"path":"{{.Path}}","q":{{json .JSON.query}},"page":"{{.Params.page | default "1"}}","agent":"{{.Headers.Get "User-Agent"}}","sid":"{{.Cookies.sid}}","tags":[{{range $i, $tag := .JSON.tags}}{{if $i}},{{end}}"{{lower $tag}}"{{end}}],"status":"{{.Vars.status}}"}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	request := httptest.NewRequest(http.MethodPost, "/tenants/acme/search", strings.NewReader(`{"query":"a \"b\"","tags":["X","Y"]}`))
+	request.Header.Set("User-Agent", "tester")
+	request.AddCookie(&http.Cookie{Name: "sid", Value: "s1"})
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+
+	if response.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", response.Code)
+	}
+	if got := response.Header().Get("X-Method"); got != "POST" {
+		t.Fatalf("X-Method = %q, want POST", got)
+	}
+	if got := response.Header().Get("X-Tenant"); got != "ACME" {
+		t.Fatalf("X-Tenant = %q, want ACME", got)
+	}
+	want := `{"path":"/tenants/acme/search","q":"a \"b\"","page":"1","agent":"tester","sid":"s1","tags":["x","y"],"status":"201"}`
+	if got := response.Body.String(); got != want {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+}
+
+func TestTemplateGeneratorFunctions(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Generated
+# $template=go
+GET /generated
+
+{{uuid}}|{{fake "integer"}}|{{integer 7 7}}|{{fake "oneOf" "red"}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	parts := strings.Split(serveBody(server, http.MethodGet, "/generated"), "|")
+	if len(parts) != 4 || len(parts[0]) != 36 || parts[1] == "" || parts[2] != "7" || parts[3] != "red" {
+		t.Fatalf("generated parts = %q", parts)
+	}
+
+	methods, err = restclient.Parse("test.http", strings.NewReader(`### Unknown
+# $template=go
+GET /unknown
+
+{{fake "nope"}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server = New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	if response.Code != http.StatusInternalServerError {
+		t.Fatalf("unknown generator status = %d, want 500", response.Code)
+	}
+}
+
+func TestTemplateLeavesPlainSectionsAlone(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Plain
+GET /plain
+
+{{.Method}} {{$missing}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if got := serveBody(server, http.MethodGet, "/plain"); got != "{{.Method}} " {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestTemplateExecutionErrorReturns500(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Broken
+# $template=go
+GET /broken
+
+{{index .Query.id 3}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	server := New(methods, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/broken?id=1", nil))
+	if response.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", response.Code)
+	}
+}
+
+func TestWarnMethodConfigReportsTemplateErrors(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Broken
+# $template=go
+GET /broken
+
+{{if .Method}}
+### Unknown engine
+# $template=mustache
+GET /unknown
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var logs bytes.Buffer
+	warnMethodConfig(slog.New(slog.NewTextHandler(&logs, nil)), methods)
+	for _, want := range []string{"invalid $template=go section", "unknown $template will be ignored"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("logs = %s, want %q", logs.String(), want)
+		}
+	}
+}
+
+func TestWarnMethodConfigSkipsUnusedVariablesInTemplates(t *testing.T) {
+	methods, err := restclient.Parse("test.http", strings.NewReader(`### Greeting
+# $template=go
+# $greeting=hello
+GET /greet
+
+{{.Vars.greeting}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var logs bytes.Buffer
+	warnMethodConfig(slog.New(slog.NewTextHandler(&logs, nil)), methods)
+	if strings.Contains(logs.String(), "unused custom variable") {
+		t.Fatalf("logs = %s, want no unused variable warning", logs.String())
+	}
+}
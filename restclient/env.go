@@ -0,0 +1,192 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Environment file names, read from the directory of each .http file the same
+// way the JetBrains HTTP client does. Private values override public ones.
+const (
+	EnvFileName        = "http-client.env.json"
+	PrivateEnvFileName = "http-client.private.env.json"
+)
+
+// sharedEnvName is the JetBrains environment merged beneath every named one.
+const sharedEnvName = "$shared"
+
+// envReferencePattern matches plain {{name}} references (no "$"), which are
+// resolved at load time from the environment and file variables.
+var envReferencePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*}}`)
+
+// Loader parses .http files, resolving {{name}} references from a named
+// environment in http-client.env.json files.
+type Loader struct {
+	// Env selects the environment, such as "dev". When empty, environment
+	// files are ignored and only file variables resolve {{name}} references.
+	Env string
+}
+
+// Load parses the given .http files in order. Files pulled in with # @import
+// are parsed where the directive appears; a file imported more than once is
+// only loaded the first time.
+func (l Loader) Load(paths []string) ([]Method, error) {
+	p := l.newParser()
+	methods, err := p.load(paths)
+	if err != nil {
+		return nil, err
+	}
+	return p.finish(methods)
+}
+
+// Imports returns every .http file pulled in by # @import directives from
+// paths, directly or transitively, so callers can watch them for changes.
+// Files are parsed with the Loader's environment, so {{name}} references
+// resolve the same way they do in Load.
+func (l Loader) Imports(paths []string) ([]string, error) {
+	p := l.newParser()
+	if _, err := p.load(paths); err != nil {
+		return nil, err
+	}
+	return p.imports, nil
+}
+
+// Parse parses one .http document. Relative # @import paths and environment
+// files resolve against the directory of source.
+func (l Loader) Parse(source string, r io.Reader) ([]Method, error) {
+	p := l.newParser()
+	methods, err := p.parse(source, r)
+	if err != nil {
+		return nil, err
+	}
+	return p.finish(methods)
+}
+
+// finish runs the checks that need every file of a Load or Parse call.
+func (p *parser) finish(methods []Method) ([]Method, error) {
+	if err := p.checkEnvFound(); err != nil {
+		return nil, err
+	}
+	if err := p.resolveExtends(methods); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+func (l Loader) newParser() *parser {
+	p := newParser()
+	p.envName = l.Env
+	return p
+}
+
+// EnvFiles returns the environment file paths that apply to the given .http
+// files, whether or not they exist yet, so callers can watch them.
+func EnvFiles(paths []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		files = append(files, filepath.Join(dir, EnvFileName), filepath.Join(dir, PrivateEnvFileName))
+	}
+	return files
+}
+
+// environment returns the selected environment's variables for the directory
+// of source, reading and caching its env files on first use.
+func (p *parser) environment(source string) (map[string]string, error) {
+	if p.envName == "" {
+		return nil, nil
+	}
+	dir := filepath.Dir(source)
+	if vars, ok := p.envs[dir]; ok {
+		return vars, nil
+	}
+	vars := make(map[string]string)
+	for _, name := range []string{EnvFileName, PrivateEnvFileName} {
+		found, err := readEnvFile(filepath.Join(dir, name), p.envName, vars)
+		if err != nil {
+			return nil, err
+		}
+		p.envFound = p.envFound || found
+	}
+	p.envs[dir] = vars
+	return vars, nil
+}
+
+func (p *parser) checkEnvFound() error {
+	if p.envName == "" || p.envFound {
+		return nil
+	}
+	return fmt.Errorf("environment %q is not defined in any %s or %s next to the request files", p.envName, EnvFileName, PrivateEnvFileName)
+}
+
+// readEnvFile merges the $shared and named environments from path into vars
+// and reports whether the named environment was present. A missing file is
+// not an error.
+func readEnvFile(path, envName string, vars map[string]string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	var environments map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &environments); err != nil {
+		return false, fmt.Errorf("%s: invalid environment file: %v", path, err)
+	}
+	for name, value := range environments[sharedEnvName] {
+		vars[name] = envValue(value)
+	}
+	selected, ok := environments[envName]
+	for name, value := range selected {
+		vars[name] = envValue(value)
+	}
+	return ok, nil
+}
+
+// envValue returns JSON strings unquoted and any other JSON value as its text.
+func envValue(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	return string(bytes.TrimSpace(raw))
+}
+
+// resolveSectionReferences resolves {{name}} references in a section's body or
+// response header. $template=go sections are left alone: they read variables
+// as {{.Vars.name}}, and {{name}} there calls a template function, such as a
+// generator.
+func resolveSectionReferences(text string, vars map[string]string, isTemplate bool) string {
+	if isTemplate {
+		return text
+	}
+	return resolveReferences(text, vars)
+}
+
+// resolveReferences replaces {{name}} references found in vars and leaves
+// unknown ones untouched, since bodies may legitimately contain braces.
+func resolveReferences(text string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return text
+	}
+	return envReferencePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := envReferencePattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return match
+	})
+}
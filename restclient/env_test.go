@@ -0,0 +1,103 @@
+package restclient
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoaderResolvesEnvironmentReferences(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		EnvFileName: `{
+  "$shared": {"version": "v1", "token": "shared"},
+  "dev": {"host": "http://api.example.test", "token": "public", "retries": 3}
+}`,
+		PrivateEnvFileName: `{"dev": {"token": "secret"}}`,
+		"api.http": `### Users
+# $status={{ status }}
+GET {{host}}/{{version}}/users
+Authorization: Bearer {{token}}
+
+{"retries":{{retries}},"template":"{{unknown}}"}
+`,
+	})
+	methods, err := Loader{Env: "dev"}.Load([]string{filepath.Join(dir, "api.http")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	method := methods[0]
+	if method.Path != "/v1/users" {
+		t.Fatalf("Path = %q, want /v1/users from full-URL target", method.Path)
+	}
+	if got := method.Headers.Get("Authorization"); got != "Bearer secret" {
+		t.Fatalf("Authorization = %q, want private env override", got)
+	}
+	if want := `{"retries":3,"template":"{{unknown}}"}`; method.Body != want {
+		t.Fatalf("Body = %q, want %q", method.Body, want)
+	}
+	if got := method.Variables["status"]; got != "{{ status }}" {
+		t.Fatalf("status = %q, want unknown reference left as-is", got)
+	}
+}
+
+func TestParseLeavesReferencesInTemplateSections(t *testing.T) {
+	methods, err := Parse("test.http", strings.NewReader(`@name = Alice
+
+### Template
+# $template=go
+GET /template
+X-Name: {{name}}
+
+{{name}} {{.Vars.name}}
+
+### Plain
+GET /plain
+X-Name: {{name}}
+
+{{name}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	template, plain := methods[0], methods[1]
+	if template.Body != "{{name}} {{.Vars.name}}" || template.Headers.Get("X-Name") != "{{name}}" {
+		t.Fatalf("template body = %q X-Name = %q, want references left for text/template", template.Body, template.Headers.Get("X-Name"))
+	}
+	if plain.Body != "Alice" || plain.Headers.Get("X-Name") != "Alice" {
+		t.Fatalf("plain body = %q X-Name = %q, want Alice", plain.Body, plain.Headers.Get("X-Name"))
+	}
+}
+
+func TestLoaderReportsUnknownEnvironment(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		EnvFileName: `{"dev": {"host": "http://localhost"}}`,
+		"api.http":  "### Users\nGET /users\n",
+	})
+	_, err := Loader{Env: "prod"}.Load([]string{filepath.Join(dir, "api.http")})
+	if err == nil || !strings.Contains(err.Error(), `environment "prod" is not defined`) {
+		t.Fatalf("Load() error = %v, want unknown environment", err)
+	}
+}
+
+func TestParseReportsUndefinedReferenceInTarget(t *testing.T) {
+	_, err := Parse("test.http", strings.NewReader("### Users\nGET {{host}}/users\n"))
+	if err == nil {
+		t.Fatal("Parse() error = nil, want undefined variable")
+	}
+	for _, want := range []string{"test.http:2:", "undefined variable {{host}}", "-env"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error = %q, want to contain %q", err, want)
+		}
+	}
+}
+
+func TestEnvFiles(t *testing.T) {
+	files := EnvFiles([]string{"a/one.http", "a/two.http", "b/three.http"})
+	want := []string{
+		filepath.Join("a", EnvFileName), filepath.Join("a", PrivateEnvFileName),
+		filepath.Join("b", EnvFileName), filepath.Join("b", PrivateEnvFileName),
+	}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Fatalf("EnvFiles() = %#v, want %#v", files, want)
+	}
+}
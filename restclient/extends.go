@@ -0,0 +1,138 @@
+package restclient
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// extendsLine returns the file line of the # $extends comment in a section,
+// falling back to the section's ### line.
+func extendsLine(method Method, lines []string, bodyStartLine int) int {
+	for i, line := range lines {
+		comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+		if !ok {
+			continue
+		}
+		if matches := commentVariablePattern.FindStringSubmatch(strings.TrimSpace(comment)); len(matches) == 3 && matches[1] == "extends" {
+			return bodyStartLine + i
+		}
+	}
+	return method.Line
+}
+
+// resolveExtends applies # $extends=Name inheritance in place. A section
+// inherits the variables, response headers and body of the named section
+// (after that section's own inheritance) wherever it does not declare its own.
+// Names resolve to a section in the same file first, then in load order.
+func (p *parser) resolveExtends(methods []Method) error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(methods))
+	var chain []string
+
+	var resolve func(i int) error
+	resolve = func(i int) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			start := slices.Index(chain, methods[i].Name)
+			cycle := append(slices.Clone(chain[start:]), methods[i].Name)
+			return p.extendsErrorf(methods[i], "$extends cycle: %s", strings.Join(cycle, " -> "))
+		}
+		baseName, ok := methods[i].Variables["extends"]
+		if !ok || slices.Contains(methods[i].InheritedVariables, "extends") {
+			state[i] = done
+			return nil
+		}
+		base := findSection(methods, baseName, methods[i].Source)
+		if base < 0 {
+			return p.extendsErrorf(methods[i], "section %q extends unknown section %q", methods[i].Name, baseName)
+		}
+		state[i] = visiting
+		chain = append(chain, methods[i].Name)
+		if err := resolve(base); err != nil {
+			return err
+		}
+		chain = chain[:len(chain)-1]
+		inheritSection(&methods[i], methods[base])
+		state[i] = done
+		return nil
+	}
+
+	for i := range methods {
+		if err := resolve(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) extendsErrorf(method Method, format string, args ...any) error {
+	line, ok := p.extendsLines[sectionKey{method.Source, method.Line}]
+	if !ok {
+		line = method.Line
+	}
+	return parseErrorf(method.Source, line, format, args...)
+}
+
+func findSection(methods []Method, name, source string) int {
+	first := -1
+	for i, method := range methods {
+		if method.Name != name {
+			continue
+		}
+		if method.Source == source {
+			return i
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return first
+}
+
+// inheritSection copies base values that child does not declare itself.
+// Values child only inherited from its file preamble are replaced by the
+// base section's, since the base is the more specific source.
+func inheritSection(child *Method, base Method) {
+	for name, value := range base.Variables {
+		if name == "extends" || declares(child.Variables, child.InheritedVariables, name) {
+			continue
+		}
+		child.Variables[name] = value
+		if name == "file" {
+			child.FileSource = cmp.Or(base.FileSource, base.Source)
+		}
+		if !slices.Contains(child.InheritedVariables, name) {
+			child.InheritedVariables = append(child.InheritedVariables, name)
+		}
+	}
+	slices.Sort(child.InheritedVariables)
+
+	for name, values := range base.Headers {
+		if declares(child.Headers, child.InheritedHeaders, name) {
+			continue
+		}
+		child.Headers[name] = slices.Clone(values)
+		if !slices.Contains(child.InheritedHeaders, name) {
+			child.InheritedHeaders = append(child.InheritedHeaders, name)
+		}
+	}
+	slices.Sort(child.InheritedHeaders)
+
+	if child.Body == "" && !declares(child.Variables, child.InheritedVariables, "file") {
+		child.Body = base.Body
+	}
+}
+
+// declares reports whether name is set in values by the section itself
+// rather than inherited.
+func declares[V any](values map[string]V, inherited []string, name string) bool {
+	_, ok := values[name]
+	return ok && !slices.Contains(inherited, name)
+}
@@ -0,0 +1,49 @@
+package restclient
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MatcherKind selects how a $query. or $header. matcher compares values.
+type MatcherKind int
+
+const (
+	// MatchEqual requires a value equal to Value.
+	MatchEqual MatcherKind = iota
+	// MatchPresent ("*") requires the query parameter or header to be present.
+	MatchPresent
+	// MatchPattern ("~regex") requires a value the regular expression matches.
+	MatchPattern
+	// MatchAbsent ("!") requires the query parameter or header to be missing.
+	MatchAbsent
+)
+
+// ValueMatcher is a parsed $query. or $header. matcher value.
+type ValueMatcher struct {
+	Kind    MatcherKind
+	Value   string
+	Pattern *regexp.Regexp
+}
+
+// ParseValueMatcher parses a matcher value: "*" for presence, "~regex" for a
+// regular expression (unanchored, so use ^ and $ to match the whole value),
+// "!" for absence, and anything else for an exact value.
+func ParseValueMatcher(value string) (ValueMatcher, error) {
+	switch value {
+	case "*":
+		return ValueMatcher{Kind: MatchPresent}, nil
+	case "!":
+		return ValueMatcher{Kind: MatchAbsent}, nil
+	}
+	expr, ok := strings.CutPrefix(value, "~")
+	if !ok {
+		return ValueMatcher{Kind: MatchEqual, Value: value}, nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return ValueMatcher{}, fmt.Errorf("invalid pattern %q: %v", expr, err)
+	}
+	return ValueMatcher{Kind: MatchPattern, Value: value, Pattern: pattern}, nil
+}
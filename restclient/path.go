@@ -0,0 +1,135 @@
+package restclient
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SegmentKind classifies one segment of a route path pattern.
+type SegmentKind int
+
+const (
+	// SegmentLiteral matches the segment text exactly.
+	SegmentLiteral SegmentKind = iota
+	// SegmentParam matches any single segment (":id") or, with a pattern,
+	// a segment the whole regular expression matches (":id(\d+)").
+	SegmentParam
+	// SegmentWildcard ("*") matches any single segment without capturing it.
+	SegmentWildcard
+	// SegmentCatchAll ("**" or ":name*") matches all remaining segments,
+	// including none. It must be the last segment; "**" captures as "rest".
+	SegmentCatchAll
+)
+
+// catchAllName is the placeholder name for a "**" segment's remainder.
+const catchAllName = "rest"
+
+// PathSegment is one "/"-separated part of a route path pattern.
+type PathSegment struct {
+	Kind    SegmentKind
+	Literal string
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ParsePath splits a route path such as "/users/:id(\d+)/files" into segments.
+// Regular expressions in :name(regex) segments are anchored to the whole
+// segment and may contain "/" only inside the parentheses.
+func ParsePath(path string) ([]PathSegment, error) {
+	parts, err := splitPattern(path)
+	if err != nil {
+		return nil, err
+	}
+	segments := make([]PathSegment, 0, len(parts))
+	for i, part := range parts {
+		segment, err := parseSegment(part)
+		if err != nil {
+			return nil, err
+		}
+		if segment.Kind == SegmentCatchAll && i != len(parts)-1 {
+			return nil, fmt.Errorf("catch-all segment %q must be the last path segment", part)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+func parseSegment(part string) (PathSegment, error) {
+	switch part {
+	case "*":
+		return PathSegment{Kind: SegmentWildcard}, nil
+	case "**":
+		return PathSegment{Kind: SegmentCatchAll, Name: catchAllName}, nil
+	}
+	param, ok := strings.CutPrefix(part, ":")
+	if !ok {
+		return PathSegment{Kind: SegmentLiteral, Literal: part}, nil
+	}
+	if name, ok := strings.CutSuffix(param, "*"); ok && !strings.Contains(name, "(") {
+		if name == "" {
+			return PathSegment{}, fmt.Errorf("catch-all parameter %q needs a name (example: \":rest*\")", part)
+		}
+		return PathSegment{Kind: SegmentCatchAll, Name: name}, nil
+	}
+	name, expr, hasPattern := strings.Cut(param, "(")
+	if name == "" {
+		return PathSegment{}, fmt.Errorf("path parameter %q needs a name (example: \":id\")", part)
+	}
+	segment := PathSegment{Kind: SegmentParam, Name: name}
+	if !hasPattern {
+		return segment, nil
+	}
+	expr, ok = strings.CutSuffix(expr, ")")
+	if !ok || expr == "" {
+		return PathSegment{}, fmt.Errorf("path parameter %q must end with a (regex) constraint (example: \":id(\\d+)\")", part)
+	}
+	pattern, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return PathSegment{}, fmt.Errorf("path parameter %q has an invalid pattern: %v", part, err)
+	}
+	segment.Pattern = pattern
+	return segment, nil
+}
+
+// splitPattern splits path on "/" and drops empty leading and trailing
+// segments, like a request path split. Inside a :name(regex) segment, "/"
+// within the parentheses does not end the segment.
+func splitPattern(path string) ([]string, error) {
+	var parts []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(path); i++ {
+		if !strings.HasPrefix(path[start:], ":") {
+			if path[i] == '/' {
+				parts = append(parts, path[start:i])
+				start = i + 1
+			}
+			continue
+		}
+		switch path[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '/':
+			if depth == 0 {
+				parts = append(parts, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in path %q", path)
+	}
+	parts = append(parts, path[start:])
+	for len(parts) > 0 && parts[0] == "" {
+		parts = parts[1:]
+	}
+	for len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return parts, nil
+}
@@ -0,0 +1,101 @@
+package restclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	segments, err := ParsePath(`/files/:name(.*\.pdf)/:id(\d+)/raw`)
+	if err != nil {
+		t.Fatalf("ParsePath() error = %v", err)
+	}
+	if len(segments) != 4 {
+		t.Fatalf("len(segments) = %d, want 4", len(segments))
+	}
+	if segments[0].Kind != SegmentLiteral || segments[0].Literal != "files" {
+		t.Fatalf("segments[0] = %#v, want literal files", segments[0])
+	}
+	name := segments[1]
+	if name.Kind != SegmentParam || name.Name != "name" || name.Pattern == nil {
+		t.Fatalf("segments[1] = %#v, want constrained name param", name)
+	}
+	if !name.Pattern.MatchString("report.pdf") || name.Pattern.MatchString("report.pdf.txt") {
+		t.Fatalf("name pattern %q is not anchored", name.Pattern)
+	}
+	if id := segments[2]; id.Name != "id" || id.Pattern.MatchString("me") {
+		t.Fatalf("segments[2] = %#v, want digit-only id param", id)
+	}
+}
+
+func TestParsePathKeepsSlashesInsideRegex(t *testing.T) {
+	segments, err := ParsePath(`/a/:x(b/c|d)/e`)
+	if err != nil {
+		t.Fatalf("ParsePath() error = %v", err)
+	}
+	if len(segments) != 3 || segments[1].Name != "x" {
+		t.Fatalf("segments = %#v, want a, :x(b/c|d), e", segments)
+	}
+}
+
+func TestParsePathWildcards(t *testing.T) {
+	segments, err := ParsePath("/v1/*/assets/**")
+	if err != nil {
+		t.Fatalf("ParsePath() error = %v", err)
+	}
+	if segments[1].Kind != SegmentWildcard {
+		t.Fatalf("segments[1] = %#v, want wildcard", segments[1])
+	}
+	if last := segments[3]; last.Kind != SegmentCatchAll || last.Name != "rest" {
+		t.Fatalf("segments[3] = %#v, want catch-all named rest", last)
+	}
+
+	segments, err = ParsePath("/files/:path*")
+	if err != nil {
+		t.Fatalf("ParsePath() error = %v", err)
+	}
+	if last := segments[1]; last.Kind != SegmentCatchAll || last.Name != "path" {
+		t.Fatalf("segments[1] = %#v, want catch-all named path", last)
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, path := range []string{`/assets/**/raw`, `/files/:*`, `/users/:`, `/users/:id(\d+`, `/users/:id([)`, `/users/:id()`, `/users/:id(\d+)x`} {
+		if _, err := ParsePath(path); err == nil {
+			t.Fatalf("ParsePath(%q) error = nil, want error", path)
+		}
+	}
+}
+
+func TestParseReportsInvalidPathPattern(t *testing.T) {
+	_, err := Parse("test.http", strings.NewReader("### Bad\n# comment\nGET /users/:id([0-9)\n"))
+	if err == nil {
+		t.Fatal("Parse() error = nil, want error")
+	}
+	for _, want := range []string{"test.http:3:", `section "Bad" has an invalid path pattern`, "invalid pattern"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error = %q, want to contain %q", err, want)
+		}
+	}
+}
+
+func TestParseKeepsQuestionMarksInsideRegex(t *testing.T) {
+	methods, err := Parse("test.http", strings.NewReader("### Colour\nGET /x/:v(colou?r)/:w(a\\?b?)?debug=1\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	method := methods[0]
+	if method.Path != `/x/:v(colou?r)/:w(a\?b?)` {
+		t.Fatalf("Path = %q, want /x/:v(colou?r)/:w(a\\?b?)", method.Path)
+	}
+	if method.Query.Get("debug") != "1" {
+		t.Fatalf("Query = %v, want debug=1", method.Query)
+	}
+	segments, err := ParsePath(method.Path)
+	if err != nil {
+		t.Fatalf("ParsePath() error = %v", err)
+	}
+	if !segments[1].Pattern.MatchString("color") || !segments[1].Pattern.MatchString("colour") {
+		t.Fatalf("segments[1] pattern = %q, want colou?r", segments[1].Pattern)
+	}
+}
@@ -2,57 +2,242 @@ package restclient
 
 import (
 	"bufio"
+	"cmp"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
 )
 
+// MethodAny is the request-line method of a section that matches every HTTP
+// method, as in "ANY /health".
+const MethodAny = "ANY"
+
 // Method is one mock request section from a REST Client-style .http file.
 //
 // Headers on the Method are response headers. Use MatchHeaders (from
-// # $header.Name=value comments) to require request headers when matching.
+// # $header.Name=value comments) to require request headers when matching,
+// MatchQuery (from # $query.name=value comments) to require query parameters,
+// MatchCookies (from # $cookie.name=value comments) to require request cookies,
+// MatchJSON (from # $body.json.path=value comments) to require fields in a
+// JSON request body, and MatchForm (from # $form.field=value comments) to
+// require fields in a urlencoded or multipart form body.
+//
+// Host is the lower-cased hostname from a full-URL request target, such as
+// "api.example.test" or "*.example.test", without the port. It is empty for
+// path-only and loopback targets, which match any host.
+//
+// Variables and Headers include @name = value variables and response headers
+// from the file preamble, and values from an # $extends=Name base section,
+// that the section does not override; their names are listed in
+// InheritedVariables and InheritedHeaders.
 type Method struct {
 	Name         string
 	Method       string
+	Host         string
 	Path         string
 	Query        url.Values
 	Comments     []string
 	Variables    map[string]string
 	MatchHeaders http.Header
+	MatchQuery   map[string]string
+	MatchCookies map[string]string
+	MatchJSON    map[string]string
+	MatchForm    map[string]string
 	Headers      http.Header
 	Body         string
 	Source       string
+	// Line is the 1-based line of the section's ### header in Source.
+	Line int
+	// FileSource is the file a $file value inherited through $extends is
+	// relative to: the base section's source. It is empty when $file is
+	// relative to Source.
+	FileSource string
+
+	InheritedVariables []string
+	InheritedHeaders   []string
 }
 
-var commentVariablePattern = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_.-]*)\s*=\s*(.*)$`)
+var commentVariablePattern = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_.\[\]-]*)\s*=\s*(.*)$`)
+
+// importPattern matches a "# @import path" directive before the first section.
+var importPattern = regexp.MustCompile(`^#\s*@import\s+(.+)$`)
+
+// fileVariablePattern matches an "@name = value" line before the first section.
+var fileVariablePattern = regexp.MustCompile(`^@([A-Za-z_][A-Za-z0-9_.-]*)\s*=\s*(.*)$`)
+
+// fileDefaults holds what a file's preamble contributes to every section:
+// the selected environment, @name = value variables and response headers.
+type fileDefaults struct {
+	env       map[string]string
+	variables map[string]string
+	headers   http.Header
+}
 
+// references returns the values for {{name}} references: the environment,
+// overridden by file variables, overridden by extra (section) variables.
+func (d fileDefaults) references(extra map[string]string) map[string]string {
+	refs := make(map[string]string, len(d.env)+len(d.variables)+len(extra))
+	maps.Copy(refs, d.env)
+	maps.Copy(refs, d.variables)
+	maps.Copy(refs, extra)
+	return refs
+}
+
+// Load parses the given .http files without an environment; see Loader.Load.
 func Load(paths []string) ([]Method, error) {
+	return Loader{}.Load(paths)
+}
+
+// Parse parses one .http document without an environment; see Loader.Parse.
+func Parse(source string, r io.Reader) ([]Method, error) {
+	return Loader{}.Parse(source, r)
+}
+
+// Imports returns every .http file pulled in by # @import directives from
+// paths without an environment; see Loader.Imports.
+func Imports(paths []string) ([]string, error) {
+	return Loader{}.Imports(paths)
+}
+
+// parser tracks the files read by one Load or Parse call for import cycle
+// detection and de-duplication, and caches environment files per directory.
+type parser struct {
+	loaded  map[string]bool
+	stack   []string
+	imports []string
+
+	envName  string
+	envs     map[string]map[string]string
+	envFound bool
+
+	// extendsLines maps a section (by Source and Line) to the line of its
+	// # $extends comment, for error messages after all files are parsed.
+	extendsLines map[sectionKey]int
+}
+
+type sectionKey struct {
+	source string
+	line   int
+}
+
+func newParser() *parser {
+	return &parser{
+		loaded:       make(map[string]bool),
+		envs:         make(map[string]map[string]string),
+		extendsLines: make(map[sectionKey]int),
+	}
+}
+
+func (p *parser) load(paths []string) ([]Method, error) {
 	var methods []Method
 	for _, path := range paths {
-		file, err := os.Open(path)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", path, err)
+		if p.loaded[absPath(path)] {
+			continue
 		}
-
-		parsed, err := Parse(path, file)
-		closeErr := file.Close()
+		parsed, err := p.parseFile(path)
 		if err != nil {
 			return nil, err
 		}
-		if closeErr != nil {
-			return nil, fmt.Errorf("%s: %w", path, closeErr)
-		}
 		methods = append(methods, parsed...)
 	}
 	return methods, nil
 }
 
-func Parse(source string, r io.Reader) ([]Method, error) {
+func (p *parser) parseFile(path string) ([]Method, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	parsed, err := p.parse(path, file)
+	closeErr := file.Close()
+	if err != nil {
+		return nil, err
+	}
+	if closeErr != nil {
+		return nil, fmt.Errorf("%s: %w", path, closeErr)
+	}
+	return parsed, nil
+}
+
+// importFile parses the file named by an @import directive on line of source.
+func (p *parser) importFile(source string, line int, target string) ([]Method, error) {
+	path := target
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(source), path)
+	}
+	abs := absPath(path)
+	if i := slices.Index(p.stack, abs); i >= 0 {
+		cycle := append(slices.Clone(p.stack[i:]), abs)
+		return nil, parseErrorf(source, line, "import cycle: %s", strings.Join(cycle, " -> "))
+	}
+	if p.loaded[abs] {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, parseErrorf(source, line, "import %s: %v", quoteSnippet(target), err)
+	}
+	p.imports = append(p.imports, path)
+	return p.parseFile(path)
+}
+
+// parsePreambleLine handles one line before the first ### section: blank
+// lines, comments, # @import directives, @name = value file variables and
+// default response headers. It returns the methods of an imported file.
+func (p *parser) parsePreambleLine(source string, lineNumber int, line string, defaults fileDefaults) ([]Method, error) {
+	trimmed := strings.TrimSpace(line)
+	if matches := importPattern.FindStringSubmatch(trimmed); len(matches) == 2 {
+		return p.importFile(source, lineNumber, strings.Trim(strings.TrimSpace(matches[1]), `"'`))
+	}
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+		return nil, nil
+	}
+	if matches := fileVariablePattern.FindStringSubmatch(trimmed); len(matches) == 3 {
+		defaults.variables[matches[1]] = resolveReferences(strings.TrimSpace(matches[2]), defaults.references(nil))
+		return nil, nil
+	}
+	if name, value, ok := strings.Cut(trimmed, ":"); ok && isHeaderName(name) {
+		defaults.headers.Add(name, resolveReferences(strings.TrimSpace(value), defaults.references(nil)))
+		return nil, nil
+	}
+	return nil, parseErrorf(source, lineNumber,
+		"content before first ### section: %s (the preamble only allows @name = value variables, Name: value response headers and # @import; start each mock with ### Name)",
+		quoteSnippet(trimmed))
+}
+
+// isHeaderName reports whether name is a valid HTTP header field name token.
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) parse(source string, r io.Reader) ([]Method, error) {
+	abs := absPath(source)
+	p.loaded[abs] = true
+	p.stack = append(p.stack, abs)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+
+	env, err := p.environment(source)
+	if err != nil {
+		return nil, err
+	}
+	defaults := fileDefaults{env: env, variables: make(map[string]string), headers: make(http.Header)}
+
 	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
@@ -69,10 +254,13 @@ func Parse(source string, r io.Reader) ([]Method, error) {
 		if current == nil {
 			return nil
 		}
-		method, err := parseSection(*current, section, bodyStartLine, sectionNameLine, source)
+		method, err := parseSection(*current, section, bodyStartLine, sectionNameLine, source, defaults)
 		if err != nil {
 			return err
 		}
+		if _, ok := method.Variables["extends"]; ok {
+			p.extendsLines[sectionKey{source, method.Line}] = extendsLine(method, section, bodyStartLine)
+		}
 		methods = append(methods, method)
 		return nil
 	}
@@ -93,8 +281,13 @@ func Parse(source string, r io.Reader) ([]Method, error) {
 				Name:         name,
 				Variables:    make(map[string]string),
 				MatchHeaders: make(http.Header),
+				MatchQuery:   make(map[string]string),
+				MatchCookies: make(map[string]string),
+				MatchJSON:    make(map[string]string),
+				MatchForm:    make(map[string]string),
 				Headers:      make(http.Header),
 				Source:       source,
+				Line:         lineNumber,
 			}
 			section = section[:0]
 			sectionNameLine = lineNumber
@@ -102,10 +295,11 @@ func Parse(source string, r io.Reader) ([]Method, error) {
 			continue
 		}
 		if current == nil {
-			if trimmed := strings.TrimSpace(line); trimmed != "" {
-				return nil, parseErrorf(source, lineNumber,
-					"content before first ### section: %s (start each mock with ### Name)", quoteSnippet(trimmed))
+			imported, err := p.parsePreambleLine(source, lineNumber, line, defaults)
+			if err != nil {
+				return nil, err
 			}
+			methods = append(methods, imported...)
 			continue
 		}
 		if len(section) == 0 {
@@ -126,7 +320,10 @@ func Parse(source string, r io.Reader) ([]Method, error) {
 	return methods, nil
 }
 
-func parseSection(method Method, lines []string, bodyStartLine, sectionNameLine int, source string) (Method, error) {
+// parseSection parses the lines after a ### line. {{name}} references in
+// variable values resolve from the environment and file variables; references
+// in the request line, headers and body may also use the section's variables.
+func parseSection(method Method, lines []string, bodyStartLine, sectionNameLine int, source string, defaults fileDefaults) (Method, error) {
 	lineAt := func(index int) int {
 		if index < 0 {
 			return sectionNameLine
@@ -153,13 +350,46 @@ func parseSection(method Method, lines []string, bodyStartLine, sectionNameLine
 		method.Comments = append(method.Comments, comment)
 		if matches := commentVariablePattern.FindStringSubmatch(comment); len(matches) == 3 {
 			key := matches[1]
-			value := strings.TrimSpace(matches[2])
+			value := resolveReferences(strings.TrimSpace(matches[2]), defaults.references(nil))
 			if headerName, ok := strings.CutPrefix(key, "header."); ok {
 				if headerName == "" {
 					return method, parseErrorf(source, lineAt(i),
 						`section %q: $header. requires a header name (example: "# $header.Authorization=Bearer token")`, method.Name)
 				}
+				if _, err := ParseValueMatcher(value); err != nil {
+					return method, parseErrorf(source, lineAt(i), "section %q: $header.%s: %v", method.Name, headerName, err)
+				}
 				method.MatchHeaders.Add(headerName, value)
+			} else if param, ok := strings.CutPrefix(key, "query."); ok {
+				if param == "" {
+					return method, parseErrorf(source, lineAt(i),
+						`section %q: $query. requires a parameter name (example: "# $query.page=*" or "# $query.sort=~^(asc|desc)$")`, method.Name)
+				}
+				if _, err := ParseValueMatcher(value); err != nil {
+					return method, parseErrorf(source, lineAt(i), "section %q: $query.%s: %v", method.Name, param, err)
+				}
+				method.MatchQuery[param] = value
+			} else if cookie, ok := strings.CutPrefix(key, "cookie."); ok {
+				if cookie == "" {
+					return method, parseErrorf(source, lineAt(i),
+						`section %q: $cookie. requires a cookie name (example: "# $cookie.session=*")`, method.Name)
+				}
+				if _, err := ParseValueMatcher(value); err != nil {
+					return method, parseErrorf(source, lineAt(i), "section %q: $cookie.%s: %v", method.Name, cookie, err)
+				}
+				method.MatchCookies[cookie] = value
+			} else if jsonPath, ok := strings.CutPrefix(key, "body.json."); ok {
+				if _, err := JSONPathSegments(jsonPath); err != nil {
+					return method, parseErrorf(source, lineAt(i),
+						`section %q: $body.json. requires a field path: %v (example: "# $body.json.customer.tier=gold" or "# $body.json.items[0].sku=A1")`, method.Name, err)
+				}
+				method.MatchJSON[jsonPath] = value
+			} else if field, ok := strings.CutPrefix(key, "form."); ok {
+				if field == "" {
+					return method, parseErrorf(source, lineAt(i),
+						`section %q: $form. requires a field name (example: "# $form.username=alice" or "# $form.avatar.filename=photo.png")`, method.Name)
+				}
+				method.MatchForm[field] = value
 			} else {
 				method.Variables[key] = value
 			}
@@ -178,7 +408,9 @@ func parseSection(method Method, lines []string, bodyStartLine, sectionNameLine
 			`section %q is missing an HTTP request line (expected "METHOD /path", example: "GET /users")`, method.Name)
 	}
 
-	rawRequest := strings.TrimSpace(lines[i])
+	vars := defaults.references(method.Variables)
+	isTemplate := cmp.Or(method.Variables["template"], defaults.variables["template"]) == "go"
+	rawRequest := resolveReferences(strings.TrimSpace(lines[i]), vars)
 	requestLine := strings.Fields(rawRequest)
 	switch {
 	case len(requestLine) == 0:
@@ -197,11 +429,16 @@ func parseSection(method Method, lines []string, bodyStartLine, sectionNameLine
 	method.Method = strings.ToUpper(requestLine[0])
 	if !isHTTPMethod(method.Method) {
 		return method, parseErrorf(source, lineAt(i),
-			`section %q has an unrecognized HTTP method %q (supported: GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS, CONNECT, TRACE)`,
+			`section %q has an unrecognized HTTP method %q (supported: GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS, CONNECT, TRACE, ANY)`,
 			method.Name, requestLine[0])
 	}
 
-	target, err := url.ParseRequestURI(requestLine[1])
+	if unresolved := envReferencePattern.FindString(requestLine[1]); unresolved != "" {
+		return method, parseErrorf(source, lineAt(i),
+			`section %q references undefined variable %s in its request target (define it in %s and select it with -env)`,
+			method.Name, unresolved, EnvFileName)
+	}
+	target, err := url.ParseRequestURI(escapeConstraintQueryMarks(requestLine[1]))
 	if err != nil {
 		return method, parseErrorf(source, lineAt(i),
 			`section %q has an invalid request target %s: %v (use a path like "/users/:id" or a full URL)`,
@@ -213,10 +450,19 @@ func parseSection(method Method, lines []string, bodyStartLine, sectionNameLine
 			`section %q has an invalid request target %s (path is required, example: "/users")`,
 			method.Name, quoteSnippet(requestLine[1]))
 	}
+	method.Host, err = targetHost(target)
+	if err != nil {
+		return method, parseErrorf(source, lineAt(i), "section %q has an invalid host in its request target: %v", method.Name, err)
+	}
 	method.Path = target.Path
 	if method.Path == "" {
 		method.Path = "/"
 	}
+	if _, err := ParsePath(method.Path); err != nil {
+		return method, parseErrorf(source, lineAt(i),
+			`section %q has an invalid path pattern %s: %v`,
+			method.Name, quoteSnippet(method.Path), err)
+	}
 	method.Query = target.Query()
 	i++
 
@@ -238,15 +484,43 @@ func parseSection(method Method, lines []string, bodyStartLine, sectionNameLine
 				`section %q has an invalid response header line %s (header name is required before ":")`,
 				method.Name, quoteSnippet(line))
 		}
-		method.Headers.Add(headerName, strings.TrimSpace(value))
+		method.Headers.Add(headerName, resolveSectionReferences(strings.TrimSpace(value), vars, isTemplate))
 		i++
 	}
 
 	bodyLines := trimTrailingBlankLines(lines[i:])
-	method.Body = strings.Join(bodyLines, "\n")
+	method.Body = resolveSectionReferences(strings.Join(bodyLines, "\n"), vars, isTemplate)
+	inheritDefaults(&method, defaults)
 	return method, nil
 }
 
+// inheritDefaults fills in preamble variables and response headers that the
+// section did not declare itself, recording their names.
+func inheritDefaults(method *Method, defaults fileDefaults) {
+	for _, name := range slices.Sorted(maps.Keys(defaults.variables)) {
+		if _, ok := method.Variables[name]; ok {
+			continue
+		}
+		method.Variables[name] = defaults.variables[name]
+		method.InheritedVariables = append(method.InheritedVariables, name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(defaults.headers)) {
+		if _, ok := method.Headers[name]; ok {
+			continue
+		}
+		method.Headers[name] = slices.Clone(defaults.headers[name])
+		method.InheritedHeaders = append(method.InheritedHeaders, name)
+	}
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
 func parseErrorf(source string, line int, format string, args ...any) error {
 	msg := fmt.Sprintf(format, args...)
 	if line > 0 {
@@ -269,10 +543,56 @@ func quoteSnippet(s string) string {
 	return fmt.Sprintf("%q", s)
 }
 
+// JSONPathSegments splits a body matcher path such as "customer.tier" or
+// "items[0].sku" into its field names and array indexes ("items", "0", "sku").
+func JSONPathSegments(path string) ([]string, error) {
+	var segments []string
+	for field := range strings.SplitSeq(path, ".") {
+		name, indexes, hasIndex := strings.Cut(field, "[")
+		if name == "" && (!hasIndex || len(segments) == 0) {
+			return nil, fmt.Errorf("empty field name in %q", path)
+		}
+		if name != "" {
+			segments = append(segments, name)
+		}
+		if !hasIndex {
+			continue
+		}
+		for index := range strings.SplitSeq("["+indexes, "[") {
+			if index == "" {
+				continue
+			}
+			digits, ok := strings.CutSuffix(index, "]")
+			if !ok || digits == "" || strings.Trim(digits, "0123456789") != "" {
+				return nil, fmt.Errorf("invalid array index %q in %q", "["+index, path)
+			}
+			segments = append(segments, digits)
+		}
+	}
+	return segments, nil
+}
+
+// targetHost returns the host constraint for a request target. A leading
+// "*." matches any subdomain.
+func targetHost(target *url.URL) (string, error) {
+	host := strings.ToLower(target.Hostname())
+	if host == "" || host == "localhost" {
+		return "", nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "", nil
+	}
+	if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return "", fmt.Errorf("%q: only a leading \"*.\" wildcard is supported (example: \"*.example.test\")", host)
+	}
+	return host, nil
+}
+
 func isHTTPMethod(method string) bool {
 	switch method {
 	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
-		http.MethodDelete, http.MethodHead, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		http.MethodDelete, http.MethodHead, http.MethodOptions, http.MethodConnect, http.MethodTrace,
+		MethodAny:
 		return true
 	default:
 		return false
@@ -287,15 +607,33 @@ func trimTrailingBlankLines(lines []string) []string {
 	return lines[:end]
 }
 
-// controlVariables are consumed by the mock server itself (not only as {{$…}} placeholders).
+// controlVariables are consumed by the mock server or parser itself (not only
+// as {{$…}} placeholders).
 var controlVariables = map[string]struct{}{
-	"status": {},
-	"delay":  {},
-	"file":   {},
+	"status":        {},
+	"delay":         {},
+	"file":          {},
+	"extends":       {},
+	"strategy":      {},
+	"weight":        {},
+	"rotateBy":      {},
+	"scenario":      {},
+	"requiredState": {},
+	"newState":      {},
+	"resource":      {},
+	"template":      {},
+	"seed":          {},
+	"seedBy":        {},
+	"instanceBy":    {},
+	"escape":        {},
 }
 
 // placeholderPattern matches {{$name}} placeholders in bodies and headers.
-var placeholderPattern = regexp.MustCompile(`\{\{\$([A-Za-z_][A-Za-z0-9_]*)}}`)
+// Dotted names such as {{$cookie.session}} refer to request values,
+// {{$name(arg,...)}} passes arguments to a generator, {{$uuid:order}} names a
+// generated value that repeats within a response, and "| filter" steps such as
+// {{$name | json}} transform the value.
+var placeholderPattern = regexp.MustCompile(`\{\{\$([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z0-9_-]+)*)(\([^(){}]*\))?(?::([A-Za-z0-9_-]+))?((?:\s*\|\s*[A-Za-z][A-Za-z0-9]*(?:\([^(){}]*\))?)*)\s*}}`)
 
 // FileDependencies returns relative $file paths referenced by methods, for watching.
 func FileDependencies(methods []Method) []string {
@@ -319,9 +657,50 @@ func FileDependencies(methods []Method) []string {
 	return deps
 }
 
+// escapeConstraintQueryMarks percent-encodes "?" inside :name(regex)
+// constraints, so url parsing keeps it in the path instead of starting the
+// query there.
+func escapeConstraintQueryMarks(target string) string {
+	var b strings.Builder
+	param := false
+	depth := 0
+	for i := 0; i < len(target); i++ {
+		c := target[i]
+		if depth == 0 && c == '?' {
+			b.WriteString(target[i:])
+			break
+		}
+		if param {
+			switch c {
+			case '\\':
+				if i+1 < len(target) {
+					b.WriteByte(c)
+					i++
+					c = target[i]
+				}
+			case '(':
+				depth++
+			case ')':
+				depth = max(depth-1, 0)
+			}
+			if depth > 0 && c == '?' {
+				b.WriteString("%3F")
+				continue
+			}
+		}
+		if depth == 0 && c == '/' {
+			param = strings.HasPrefix(target[i+1:], ":")
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
 // UnusedCustomVariables returns names of comment variables that are not control
-// variables ($status, $delay, $file) and never appear as {{$name}} in the
-// section body or response headers. Callers should warn; these are not errors.
+// variables (such as $status, $file or $strategy) and never appear as {{$name}} in the
+// section body or response headers. Variables inherited from the file preamble
+// are shared by every section and are not reported. Callers should warn; these
+// are not errors.
 func UnusedCustomVariables(method Method) []string {
 	if len(method.Variables) == 0 {
 		return nil
@@ -332,7 +711,7 @@ func UnusedCustomVariables(method Method) []string {
 		if _, ok := controlVariables[name]; ok {
 			continue
 		}
-		if used[name] {
+		if used[name] || slices.Contains(method.InheritedVariables, name) {
 			continue
 		}
 		unused = append(unused, name)
@@ -345,7 +724,10 @@ func placeholderNames(method Method) map[string]bool {
 	used := make(map[string]bool)
 	collect := func(text string) {
 		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
-			if len(match) == 2 {
+			// {{$name(args)}} and {{$name:instance}} always call the
+			// generator; only {{$name}}, with or without filters, reads a
+			// section variable.
+			if len(match) == 5 && match[2] == "" && match[3] == "" {
 				used[match[1]] = true
 			}
 		}
@@ -2,10 +2,29 @@ package restclient
 
 import (
 	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 )
 
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return dir
+}
+
 func TestParse(t *testing.T) {
 	input := `### Create User
 # creates a user
@@ -102,6 +121,103 @@ Content-Type: application/json
 	}
 }
 
+func TestParseJSONBodyMatchers(t *testing.T) {
+	input := `### Gold order
+# $body.json.customer.tier=gold
+# $body.json.items[0].sku=A1
+POST /orders
+
+gold
+`
+	methods, err := Parse("test.http", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := methods[0].MatchJSON["customer.tier"]; got != "gold" {
+		t.Fatalf("customer.tier match = %q, want gold", got)
+	}
+	if got := methods[0].MatchJSON["items[0].sku"]; got != "A1" {
+		t.Fatalf("items[0].sku match = %q, want A1", got)
+	}
+	if len(methods[0].Variables) != 0 {
+		t.Fatalf("variables = %#v, want body matchers kept out of variables", methods[0].Variables)
+	}
+}
+
+func TestParseFormMatchers(t *testing.T) {
+	input := `### Upload avatar
+# $form.username=alice
+# $form.avatar.contentType=image/png
+POST /upload
+
+ok
+`
+	methods, err := Parse("test.http", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := methods[0].MatchForm["username"]; got != "alice" {
+		t.Fatalf("username match = %q, want alice", got)
+	}
+	if got := methods[0].MatchForm["avatar.contentType"]; got != "image/png" {
+		t.Fatalf("avatar.contentType match = %q, want image/png", got)
+	}
+}
+
+func TestParseQueryMatchers(t *testing.T) {
+	methods, err := Parse("test.http", strings.NewReader(`### Users
+# $query.page=*
+# $query.sort=~^(asc|desc)$
+# $query.debug=!
+GET /users
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := map[string]string{"page": "*", "sort": "~^(asc|desc)$", "debug": "!"}
+	if !reflect.DeepEqual(methods[0].MatchQuery, want) {
+		t.Fatalf("MatchQuery = %#v, want %#v", methods[0].MatchQuery, want)
+	}
+	if len(methods[0].Variables) != 0 {
+		t.Fatalf("Variables = %#v, want query matchers kept out of variables", methods[0].Variables)
+	}
+}
+
+func TestParseCookieMatchers(t *testing.T) {
+	methods, err := Parse("test.http", strings.NewReader(`### Dashboard
+# $cookie.session=*
+# $cookie.theme=dark
+GET /dashboard
+
+{{$cookie.session}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := map[string]string{"session": "*", "theme": "dark"}
+	if !reflect.DeepEqual(methods[0].MatchCookies, want) {
+		t.Fatalf("MatchCookies = %#v, want %#v", methods[0].MatchCookies, want)
+	}
+	if unused := UnusedCustomVariables(methods[0]); len(unused) != 0 {
+		t.Fatalf("UnusedCustomVariables() = %#v, want cookie matchers kept out of variables", unused)
+	}
+}
+
+func TestJSONPathSegments(t *testing.T) {
+	segments, err := JSONPathSegments("items[0][2].sku")
+	if err != nil {
+		t.Fatalf("JSONPathSegments() error = %v", err)
+	}
+	if got := strings.Join(segments, "/"); got != "items/0/2/sku" {
+		t.Fatalf("segments = %q, want items/0/2/sku", got)
+	}
+	for _, path := range []string{"", "a..b", "[0]", "items[x]", "items[0", "a."} {
+		if _, err := JSONPathSegments(path); err == nil {
+			t.Fatalf("JSONPathSegments(%q) error = nil, want error", path)
+		}
+	}
+}
+
 func TestFileDependencies(t *testing.T) {
 	methods := []Method{
 		{Variables: map[string]string{"file": "users.json"}},
@@ -150,6 +266,40 @@ ok
 	}
 }
 
+func TestParseAcceptsAnyMethod(t *testing.T) {
+	methods, err := Parse("test.http", strings.NewReader("### Health\nANY /health\n\nok\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if methods[0].Method != MethodAny {
+		t.Fatalf("Method = %q, want %q", methods[0].Method, MethodAny)
+	}
+}
+
+func TestParseRequestTargetHost(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "/users", want: ""},
+		{target: "https://API.Example.test:8443/users", want: "api.example.test"},
+		{target: "http://*.example.test/users", want: "*.example.test"},
+		{target: "http://localhost:8080/users", want: ""},
+		{target: "http://127.0.0.1/users", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			methods, err := Parse("test.http", strings.NewReader("### Users\nGET "+tt.target+"\n"))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if methods[0].Host != tt.want || methods[0].Path != "/users" {
+				t.Fatalf("Host = %q Path = %q, want %q /users", methods[0].Host, methods[0].Path, tt.want)
+			}
+		})
+	}
+}
+
 func TestUnusedCustomVariables(t *testing.T) {
 	methods, err := Parse("test.http", strings.NewReader(`### Used
 # $status=200
@@ -184,6 +334,26 @@ hi {{$name}}
 	}
 }
 
+func TestUnusedCustomVariablesPlaceholderArguments(t *testing.T) {
+	methods, err := Parse("test.http", strings.NewReader(`### Args
+# $greeting=hello
+# $order=o-1
+# $unused=1
+GET /hi
+X-Order: {{$order:first}}
+
+{{$greeting | upper}} {{$integer(1,100)}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	// {{$order:first}} names a generated value and never reads $order.
+	unused := UnusedCustomVariables(methods[0])
+	if !slices.Equal(unused, []string{"order", "unused"}) {
+		t.Fatalf("UnusedCustomVariables() = %#v, want [order unused]", unused)
+	}
+}
+
 func TestParseErrors(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -259,6 +429,53 @@ GET /users
 `,
 			want: []string{"test.http:2:", "$header. requires a header name"},
 		},
+		{
+			name: "invalid body json path",
+			input: `### Order
+# $body.json.items[x]=1
+POST /orders
+`,
+			want: []string{"test.http:2:", "$body.json. requires a field path"},
+		},
+		{
+			name: "invalid query matcher pattern",
+			input: `### Users
+# $query.sort=~(asc
+GET /users
+`,
+			want: []string{"test.http:2:", `section "Users": $query.sort: invalid pattern "(asc"`},
+		},
+		{
+			name: "invalid header matcher pattern",
+			input: `### Users
+# $header.Accept=~[json
+GET /users
+`,
+			want: []string{"test.http:2:", `section "Users": $header.Accept: invalid pattern`},
+		},
+		{
+			name: "empty cookie matcher name",
+			input: `### Users
+# $cookie.=abc
+GET /users
+`,
+			want: []string{"test.http:2:", "$cookie. requires a cookie name"},
+		},
+		{
+			name: "invalid host wildcard",
+			input: `### Users
+GET http://api.*.test/users
+`,
+			want: []string{"test.http:2:", `section "Users" has an invalid host`, `only a leading "*." wildcard`},
+		},
+		{
+			name: "empty form matcher name",
+			input: `### Login
+# $form.=alice
+POST /login
+`,
+			want: []string{"test.http:2:", "$form. requires a field name"},
+		},
 		{
 			name: "error line in later section",
 			input: `### Good
@@ -305,3 +522,252 @@ ok
 		t.Fatalf("methods = %#v", methods)
 	}
 }
+
+func TestLoadResolvesImportsRelativeToImportingFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"api.http":           "# @import ./common/auth.http\n# @import ./common/health.http\n\n### Users\nGET /users\n\nusers\n",
+		"common/auth.http":   "# @import ./errors.http\n\n### Login\nPOST /login\n\nok\n",
+		"common/errors.http": "### Not found\nGET /missing\n\nmissing\n",
+		"common/health.http": "# @import errors.http\n\n### Health\nGET /health\n\nok\n",
+	})
+	api := filepath.Join(dir, "api.http")
+	methods, err := Load([]string{api})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var names []string
+	for _, method := range methods {
+		names = append(names, method.Name)
+	}
+	// errors.http is imported twice but loaded once, where it first appears.
+	if got := strings.Join(names, ","); got != "Not found,Login,Health,Users" {
+		t.Fatalf("method order = %q, want imports first and de-duplicated", got)
+	}
+	if got := methods[0].Source; got != filepath.Join(dir, "common", "errors.http") {
+		t.Fatalf("imported Source = %q, want imported file path", got)
+	}
+
+	imports, err := Imports([]string{api})
+	if err != nil {
+		t.Fatalf("Imports() error = %v", err)
+	}
+	if len(imports) != 3 {
+		t.Fatalf("Imports() = %#v, want auth, errors and health", imports)
+	}
+}
+
+func TestLoadReportsImportCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.http": "# @import b.http\n### A\nGET /a\n",
+		"b.http": "\n# @import a.http\n### B\nGET /b\n",
+	})
+	_, err := Load([]string{filepath.Join(dir, "a.http")})
+	if err == nil {
+		t.Fatal("Load() error = nil, want import cycle")
+	}
+	for _, want := range []string{"b.http:2:", "import cycle", "a.http -> ", "b.http -> "} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error = %q, want to contain %q", err, want)
+		}
+	}
+}
+
+func TestLoadReportsMissingImport(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"api.http": "# @import ./missing.http\n"})
+	_, err := Load([]string{filepath.Join(dir, "api.http")})
+	if err == nil {
+		t.Fatal("Load() error = nil, want missing import")
+	}
+	for _, want := range []string{"api.http:1:", `import "./missing.http"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error = %q, want to contain %q", err, want)
+		}
+	}
+}
+
+func TestParseFilePreambleDefaults(t *testing.T) {
+	input := `# Shared settings for every section below.
+@prefix = /api
+@tenant = acme
+Content-Type: application/json
+Access-Control-Allow-Origin: *
+
+### Users
+GET {{prefix}}/users
+
+{"tenant":"{{$tenant}}"}
+
+### Legacy users
+# $tenant=legacy
+GET {{prefix}}/legacy
+Content-Type: text/plain
+
+{{tenant}}
+`
+	methods, err := Parse("test.http", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	users, legacy := methods[0], methods[1]
+	if users.Path != "/api/users" || legacy.Path != "/api/legacy" {
+		t.Fatalf("paths = %q, %q, want {{prefix}} resolved", users.Path, legacy.Path)
+	}
+	if users.Variables["tenant"] != "acme" || users.Headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("users = %#v, want inherited tenant and Content-Type", users)
+	}
+	if got := strings.Join(users.InheritedVariables, ","); got != "prefix,tenant" {
+		t.Fatalf("users InheritedVariables = %q, want prefix,tenant", got)
+	}
+	if got := strings.Join(users.InheritedHeaders, ","); got != "Access-Control-Allow-Origin,Content-Type" {
+		t.Fatalf("users InheritedHeaders = %q", got)
+	}
+
+	if legacy.Variables["tenant"] != "legacy" || legacy.Body != "legacy" {
+		t.Fatalf("legacy tenant = %q body = %q, want section override", legacy.Variables["tenant"], legacy.Body)
+	}
+	if got := legacy.Headers.Values("Content-Type"); len(got) != 1 || got[0] != "text/plain" {
+		t.Fatalf("legacy Content-Type = %#v, want section override only", got)
+	}
+	if got := strings.Join(legacy.InheritedHeaders, ","); got != "Access-Control-Allow-Origin" {
+		t.Fatalf("legacy InheritedHeaders = %q", got)
+	}
+	if unused := UnusedCustomVariables(users); len(unused) != 0 {
+		t.Fatalf("UnusedCustomVariables() = %#v, want inherited variables ignored", unused)
+	}
+}
+
+func TestParseRejectsInvalidPreambleLine(t *testing.T) {
+	_, err := Parse("test.http", strings.NewReader("@ok = 1\nGET http://example.test/users\n### Users\nGET /users\n"))
+	if err == nil {
+		t.Fatal("Parse() error = nil, want preamble error")
+	}
+	for _, want := range []string{"test.http:2:", "content before first ###", "@name = value"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error = %q, want to contain %q", err, want)
+		}
+	}
+}
+
+func TestParseExtendsInheritsFromBaseSection(t *testing.T) {
+	input := `### Base user response
+# $status=200
+# $role=member
+GET /users/:id
+Content-Type: application/json
+X-Version: 1
+
+{"id":"{{$id}}","role":"{{$role}}"}
+
+### Admin
+# $extends=Base user response
+# $role=admin
+GET /admins/:id
+X-Version: 2
+
+### Guest
+# $extends=Admin
+GET /guests/:id
+
+guest
+`
+	methods, err := Parse("test.http", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	admin, guest := methods[1], methods[2]
+	if admin.Variables["role"] != "admin" || admin.Variables["status"] != "200" {
+		t.Fatalf("admin variables = %#v, want own role and inherited status", admin.Variables)
+	}
+	if admin.Body != methods[0].Body {
+		t.Fatalf("admin body = %q, want base body", admin.Body)
+	}
+	if admin.Headers.Get("Content-Type") != "application/json" || admin.Headers.Get("X-Version") != "2" {
+		t.Fatalf("admin headers = %#v, want inherited Content-Type and own X-Version", admin.Headers)
+	}
+	if got := strings.Join(admin.InheritedVariables, ","); got != "status" {
+		t.Fatalf("admin InheritedVariables = %q, want status", got)
+	}
+	if got := strings.Join(admin.InheritedHeaders, ","); got != "Content-Type" {
+		t.Fatalf("admin InheritedHeaders = %q, want Content-Type", got)
+	}
+
+	if guest.Variables["role"] != "admin" || guest.Headers.Get("X-Version") != "2" || guest.Body != "guest" {
+		t.Fatalf("guest = %#v, want chained inheritance with own body", guest)
+	}
+	if guest.Variables["extends"] != "Admin" {
+		t.Fatalf("guest extends = %q, want its own target", guest.Variables["extends"])
+	}
+	if unused := UnusedCustomVariables(admin); len(unused) != 0 {
+		t.Fatalf("UnusedCustomVariables() = %#v, want $extends treated as control variable", unused)
+	}
+}
+
+func TestLoadExtendsSectionFromImportedFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.http": "### Base\n# $delay=10\nGET /base\nContent-Type: text/plain\n\nbase\n",
+		"api.http":  "# @import base.http\n\n### Users\n# $extends=Base\nGET /users\n",
+	})
+	methods, err := Load([]string{filepath.Join(dir, "api.http")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	users := methods[1]
+	if users.Variables["delay"] != "10" || users.Body != "base" || users.Headers.Get("Content-Type") != "text/plain" {
+		t.Fatalf("users = %#v, want values from imported Base", users)
+	}
+}
+
+func TestLoadExtendsKeepsBaseFileSource(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"common/base.http": "### Base\n# $file=users.json\nGET /base\n",
+		"api.http":         "# @import common/base.http\n\n### Users\n# $extends=Base\nGET /users\n",
+	})
+	methods, err := Load([]string{filepath.Join(dir, "api.http")})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	base, users := methods[0], methods[1]
+	if users.Variables["file"] != "users.json" || users.FileSource != base.Source {
+		t.Fatalf("users $file = %q relative to %q, want users.json relative to %q", users.Variables["file"], users.FileSource, base.Source)
+	}
+	if base.FileSource != "" {
+		t.Fatalf("base FileSource = %q, want empty for its own $file", base.FileSource)
+	}
+}
+
+func TestParseExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "unknown section",
+			input: "### Users\n# $status=200\n# $extends=Missing\nGET /users\n",
+			want:  []string{"test.http:3:", `section "Users" extends unknown section "Missing"`},
+		},
+		{
+			name:  "self reference",
+			input: "### Users\n# $extends=Users\nGET /users\n",
+			want:  []string{"test.http:2:", "$extends cycle: Users -> Users"},
+		},
+		{
+			name:  "cycle",
+			input: "### A\n# $extends=B\nGET /a\n\n### B\n# $extends=A\nGET /b\n",
+			want:  []string{"test.http:2:", "$extends cycle: A -> B -> A"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("test.http", strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("Parse() error = nil")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Fatalf("Parse() error = %q, want %q", err, want)
+				}
+			}
+		})
+	}
+}